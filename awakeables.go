@@ -15,7 +15,7 @@ type ResolveAwakeableOption = options.ResolveAwakeableOption
 // Awakeable returns a Restate awakeable; a 'promise' to a future
 // value or error, that can be resolved or rejected by other services.
 func Awakeable[T any](ctx Context, options ...options.AwakeableOption) AwakeableFuture[T] {
-	return genericfutures.AwakeableFuture[T]{AwakeableFuture: ctx.inner().Awakeable(options...)}
+	return genericfutures.AwakeableFuture[T]{AwakeableFuture: ctx.inner().Awakeable(withRegisteredCodec[T](options)...)}
 }
 
 // AwakeableFuture is a 'promise' to a future value or error, that can be resolved or rejected by other services.
@@ -33,7 +33,7 @@ type AwakeableFuture[T any] interface {
 // ResolveAwakeable allows an awakeable (not necessarily from this service) to be
 // resolved with a particular value.
 func ResolveAwakeable[T any](ctx Context, id string, value T, options ...options.ResolveAwakeableOption) {
-	ctx.inner().ResolveAwakeable(id, value, options...)
+	ctx.inner().ResolveAwakeable(id, value, withRegisteredCodec[T](options)...)
 }
 
 // RejectAwakeable allows an awakeable (not necessarily from this service) to be
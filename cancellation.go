@@ -0,0 +1,42 @@
+package restate
+
+import (
+	"github.com/restatedev/sdk-go/internal/errors"
+)
+
+// IsCancellation reports whether err is, or wraps, the [TerminalError], with code 409, returned
+// by the operations interrupted because the invocation was cancelled. The SDK marks these
+// errors when it receives the cancel signal: other terminal errors with code 409, such as the
+// failures of calls to invocations which were cancelled, are not cancellations.
+//
+// For more info about cancellations, see https://docs.restate.dev/operate/invocation/#cancelling-invocations
+func IsCancellation(err error) bool {
+	return errors.IsCancellation(err)
+}
+
+// OnCancel registers fn to run when the invocation is cancelled and its handler returns an
+// error, usually the cancellation error of the interrupted operation (see [IsCancellation]), for
+// example to undo the steps taken so far:
+//
+//	restate.OnCancel(ctx, func(ctx restate.Context) {
+//		restate.ServiceSend(ctx, "Payments", "Refund").Send(paymentID)
+//	})
+//
+// Hooks run before the error is classified (see [WithErrorClassifier]), in the reverse order of
+// their registration, in a shielded scope: see [Shield].
+// They can use the context as usual, and are executed again on retries until the invocation
+// completes.
+func OnCancel(ctx Context, fn func(ctx Context)) {
+	ctx.inner().OnCancel(func() { fn(ctx) })
+}
+
+// Shield runs fn without letting the cancellation of the invocation interrupt the
+// operations it awaits, so that cleanup steps such as calls, [Run] functions and state
+// writes complete. If the invocation is cancelled meanwhile, the first operation awaited
+// after fn returns fails with a cancellation error instead.
+//
+// Shielding doesn't prevent the runtime from cancelling the calls made by the invocation
+// before it was cancelled.
+func Shield(ctx Context, fn func()) {
+	ctx.inner().Shield(fn)
+}
@@ -0,0 +1,25 @@
+package restate_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	internalerrors "github.com/restatedev/sdk-go/internal/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCancellation(t *testing.T) {
+	err := internalerrors.NewCancellationError()
+	require.True(t, restate.IsCancellation(err))
+	require.True(t, restate.IsCancellation(fmt.Errorf("awaiting call: %w", err)))
+	require.True(t, restate.IsCancellation(restate.ToTerminalError(err)))
+
+	require.False(t, restate.IsCancellation(nil))
+	require.False(t, restate.IsCancellation(errors.New("cancelled")))
+	require.False(t, restate.IsCancellation(restate.ToTerminalError(errors.New("already locked"), restate.WithErrorCode(409))))
+	for _, message := range []string{"Cancelled", "cancelled", "canceled"} {
+		require.False(t, restate.IsCancellation(restate.ToTerminalError(errors.New(message), restate.WithErrorCode(409))), message)
+	}
+}
@@ -0,0 +1,521 @@
+package restate
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/restatedev/sdk-go/internal/errors"
+	"github.com/restatedev/sdk-go/internal/options"
+)
+
+// CircuitBreakerServiceName is the name of the virtual object returned by
+// [NewCircuitBreakerService]. Each key of the object is a circuit breaker.
+const CircuitBreakerServiceName = "RestateCircuitBreaker"
+
+// CircuitBreakerMetadataKey is the terminal error metadata key set to the name of the circuit
+// breaker which rejected a call, on the 503 errors returned while the breaker is open.
+const CircuitBreakerMetadataKey = "restate.circuitBreaker"
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets all calls through, counting their consecutive failures.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects all calls, until its open timeout elapses.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single probe call through: the circuit closes if it succeeds,
+	// and opens again if it fails.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerConfig configures a circuit breaker, through the Configure handler of the
+// object returned by [NewCircuitBreakerService] or with [ConfigureCircuitBreaker].
+// Unconfigured circuit breakers use the defaults.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures opening the circuit, 5 by
+	// default.
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+	// OpenTimeout is how long the circuit stays open before letting a probe call through,
+	// 30 seconds by default. A probe not completed within OpenTimeout lets another one through.
+	OpenTimeout time.Duration `json:"openTimeout,omitempty"`
+}
+
+func (c CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 5
+	}
+	if c.OpenTimeout == 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	return c
+}
+
+// CircuitBreakerStatus is the state of a circuit breaker, as returned by the Status handler of
+// the object returned by [NewCircuitBreakerService].
+type CircuitBreakerStatus struct {
+	Config CircuitBreakerConfig `json:"config"`
+	State  CircuitState         `json:"state"`
+	// Failures is the number of consecutive failures while closed.
+	Failures int `json:"failures"`
+	// OpenedAt is the time the circuit last opened.
+	OpenedAt time.Time `json:"openedAt,omitzero"`
+	// Waiting is the number of calls waiting for the circuit to let them through.
+	Waiting int `json:"waiting"`
+}
+
+type circuit struct {
+	State    CircuitState `json:"state"`
+	Failures int          `json:"failures,omitempty"`
+	OpenedAt time.Time    `json:"openedAt,omitzero"`
+	// Generation changes with every transition and probe, so that stale timeouts and
+	// outcomes are ignored.
+	Generation uint64 `json:"generation"`
+	Probing    bool   `json:"probing,omitempty"`
+}
+
+// circuitPermit lets a call through a circuit breaker.
+type circuitPermit struct {
+	Probe      bool   `json:"probe,omitempty"`
+	Generation uint64 `json:"generation,omitempty"`
+}
+
+type circuitWaitRequest struct {
+	AwakeableID string `json:"awakeableId"`
+}
+
+type circuitOutcome struct {
+	Success bool `json:"success"`
+	// Released is set when the call was cancelled, which is neither a success nor a failure:
+	// the permit is released, letting another probe through if it was one.
+	Released bool `json:"released,omitempty"`
+	circuitPermit
+}
+
+type circuitTimeout struct {
+	Generation uint64 `json:"generation"`
+}
+
+var (
+	circuitBreakerConfig  = NewStateKey[*CircuitBreakerConfig]("config")
+	circuitBreakerState   = NewStateKey[*circuit]("state")
+	circuitBreakerWaiters = NewStateKey[[]string]("waiters")
+)
+
+type withCircuitBreaker struct {
+	name string
+	wait bool
+}
+
+var _ options.RunOption = withCircuitBreaker{}
+
+func (w withCircuitBreaker) BeforeRun(opts *options.RunOptions) {
+	opts.CircuitBreaker = w.name
+	opts.CircuitBreakerWait = w.wait
+}
+
+// WithCircuitBreaker guards a [Run] or [RunVoid] function with the circuit breaker of the
+// given name. While the circuit is open, the function is not run, and fails fast with a 503
+// terminal error carrying the name of the breaker under [CircuitBreakerMetadataKey].
+//
+// Retryable failures of the function are recorded by the breaker, and retried with the
+// intervals and limits of the retry options, as long as the circuit lets them through. Once
+// the retry limits are reached, the last failure is returned as a terminal error. Terminal
+// errors returned by the function are returned right away and, like successes, close the
+// circuit. Cancellations are not recorded.
+//
+// Circuit breakers are implemented by the virtual object returned by
+// [NewCircuitBreakerService], which must be bound to a server of the same Restate deployment.
+// Each guarded function first calls its shared Allow handler, so closed circuits don't
+// serialize their callers; only half-open circuits, granting a single probe, and waiting calls
+// go through its exclusive handlers. [RunAsync] doesn't support circuit breakers.
+func WithCircuitBreaker(name string) withCircuitBreaker {
+	return withCircuitBreaker{name: name}
+}
+
+// WithCircuitBreakerWait is like [WithCircuitBreaker], but waits for an open circuit to let the
+// function through rather than failing fast. The invocation suspends while waiting.
+func WithCircuitBreakerWait(name string) withCircuitBreaker {
+	return withCircuitBreaker{name: name, wait: true}
+}
+
+// ConfigureCircuitBreaker sets the configuration of the circuit breaker with the given name.
+func ConfigureCircuitBreaker(ctx Context, name string, config CircuitBreakerConfig) TerminalError {
+	_, err := Object[Void](ctx, CircuitBreakerServiceName, name, "Configure").Request(config)
+	return err
+}
+
+// GetCircuitBreaker returns the status of the circuit breaker with the given name.
+func GetCircuitBreaker(ctx Context, name string) (CircuitBreakerStatus, TerminalError) {
+	return Object[CircuitBreakerStatus](ctx, CircuitBreakerServiceName, name, "Status").Request(Void{})
+}
+
+// IsCircuitOpen reports whether err was returned because a circuit breaker was open.
+func IsCircuitOpen(err error) bool {
+	terminal := AsTerminalError(err)
+	return terminal != nil && terminal.Metadata().Get(CircuitBreakerMetadataKey) != ""
+}
+
+// runWithCircuitBreaker runs fn through the circuit breaker set in o, until it succeeds, fails
+// with a terminal error, is rejected by the breaker or reaches the retry limits.
+func runWithCircuitBreaker(ctx Context, fn func(ctx RunContext) (any, error), output any, o options.RunOptions, opts []options.RunOption) TerminalError {
+	var start time.Time
+	if o.MaxRetryDuration != nil {
+		var err TerminalError
+		if start, err = now(ctx); err != nil {
+			return err
+		}
+	}
+
+	interval, factor, maxInterval := 50*time.Millisecond, 2.0, 2*time.Second
+	if o.InitialRetryInterval != nil {
+		interval = *o.InitialRetryInterval
+	}
+	if o.RetryIntervalFactor != nil {
+		factor = float64(*o.RetryIntervalFactor)
+	}
+	if o.MaxRetryInterval != nil {
+		maxInterval = *o.MaxRetryInterval
+	}
+
+	for attempt := uint(1); ; attempt++ {
+		permit, err := passCircuitBreaker(ctx, o.CircuitBreaker, o.CircuitBreakerWait)
+		if err != nil {
+			return err
+		}
+		err = ctx.inner().Run(fn, output, opts...)
+		if IsCancellation(err) {
+			ObjectSend(ctx, CircuitBreakerServiceName, o.CircuitBreaker, "Record").
+				Send(circuitOutcome{Released: true, circuitPermit: permit})
+			return err
+		}
+		failed := err != nil && err.Metadata().Get(errors.CircuitBreakerFailureMetadataKey) != ""
+		ObjectSend(ctx, CircuitBreakerServiceName, o.CircuitBreaker, "Record").
+			Send(circuitOutcome{Success: !failed, circuitPermit: permit})
+		if !failed {
+			return err
+		}
+
+		// The marker of the failures is internal to the SDK
+		failure := errors.WithoutMetadata(err, errors.CircuitBreakerFailureMetadataKey)
+		if o.MaxRetryAttempts != nil && attempt >= *o.MaxRetryAttempts {
+			return failure
+		}
+		if o.MaxRetryDuration != nil {
+			now, nowErr := now(ctx)
+			if nowErr != nil {
+				return nowErr
+			}
+			if now.Add(interval).Sub(start) > *o.MaxRetryDuration {
+				return failure
+			}
+		}
+		if err := Sleep(ctx, interval); err != nil {
+			return err
+		}
+		interval = min(maxInterval, time.Duration(math.Min(float64(interval)*factor, math.MaxInt64)))
+	}
+}
+
+func passCircuitBreaker(ctx Context, name string, wait bool) (circuitPermit, TerminalError) {
+	if !wait {
+		return Object[circuitPermit](ctx, CircuitBreakerServiceName, name, "Allow").Request(Void{})
+	}
+	awakeable := Awakeable[circuitPermit](ctx)
+	ObjectSend(ctx, CircuitBreakerServiceName, name, "Wait").Send(circuitWaitRequest{AwakeableID: awakeable.Id()})
+	return awakeable.Result()
+}
+
+// NewCircuitBreakerService returns the virtual object implementing [WithCircuitBreaker],
+// named [CircuitBreakerServiceName]. Bind it once to a server of the Restate deployment:
+//
+//	server.NewRestate().Bind(restate.NewCircuitBreakerService())
+//
+// Circuit breakers are configured at runtime with the Configure handler, taking a
+// [CircuitBreakerConfig], closed with the Reset handler, and inspected with the shared Status
+// handler, returning a [CircuitBreakerStatus]. An open circuit becomes half-open through a
+// delayed invocation of its Timeout handler.
+//
+// Calls are let through by the shared Allow handler while the circuit is closed, and by the
+// exclusive Probe handler, which Allow defers to, while it is half-open.
+func NewCircuitBreakerService(opts ...options.ServiceDefinitionOption) ServiceDefinition {
+	return NewObject(CircuitBreakerServiceName, append([]options.ServiceDefinitionOption{
+		WithDocumentation("Durable circuit breakers, keyed by dependency name."),
+		WithStateKeys(circuitBreakerConfig, circuitBreakerState, circuitBreakerWaiters),
+	}, opts...)...).
+		Handler("Allow", NewObjectSharedHandler(circuitBreakerAllow)).
+		Handler("Probe", NewObjectHandler(circuitBreakerProbe)).
+		Handler("Wait", NewObjectHandler(circuitBreakerWait)).
+		Handler("Record", NewObjectHandler(circuitBreakerRecord)).
+		Handler("Timeout", NewObjectHandler(circuitBreakerTimeout)).
+		Handler("Configure", NewObjectHandler(circuitBreakerConfigure)).
+		Handler("Reset", NewObjectHandler(circuitBreakerReset)).
+		Handler("Status", NewObjectSharedHandler(circuitBreakerStatus))
+}
+
+// circuitBreaker is the state of a circuit breaker loaded by an exclusive handler.
+type circuitBreaker struct {
+	config CircuitBreakerConfig
+	state  circuit
+}
+
+func loadCircuitBreaker(ctx ObjectContext) (*circuitBreaker, TerminalError) {
+	config, err := circuitBreakerConfig.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state, err := circuitBreakerState.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := &circuitBreaker{state: circuit{State: CircuitClosed}}
+	if config != nil {
+		b.config = *config
+	}
+	b.config = b.config.withDefaults()
+	if state != nil {
+		b.state = *state
+	}
+	return b, nil
+}
+
+func (b *circuitBreaker) save(ctx ObjectContext) {
+	circuitBreakerState.Set(ctx, &b.state)
+}
+
+// allow lets a call through if the circuit allows it, granting the probe of a half-open
+// circuit.
+func (b *circuitBreaker) allow(ctx ObjectContext) (circuitPermit, bool) {
+	switch b.state.State {
+	case CircuitClosed:
+		return circuitPermit{}, true
+	case CircuitHalfOpen:
+		if b.state.Probing {
+			return circuitPermit{}, false
+		}
+		b.state.Probing = true
+		b.scheduleTimeout(ctx)
+		return circuitPermit{Probe: true, Generation: b.state.Generation}, true
+	default:
+		return circuitPermit{}, false
+	}
+}
+
+// scheduleTimeout starts a new generation, timing out after the open timeout.
+func (b *circuitBreaker) scheduleTimeout(ctx ObjectContext) {
+	b.state.Generation++
+	ObjectSend(ctx, CircuitBreakerServiceName, Key(ctx), "Timeout").
+		Send(circuitTimeout{Generation: b.state.Generation}, WithDelay(b.config.OpenTimeout))
+}
+
+func (b *circuitBreaker) open(ctx ObjectContext) error {
+	now, err := now(ctx)
+	if err != nil {
+		return err
+	}
+	b.state.State = CircuitOpen
+	b.state.OpenedAt = now
+	b.state.Probing = false
+	b.scheduleTimeout(ctx)
+	return nil
+}
+
+// close closes the circuit, letting all the waiting calls through.
+func (b *circuitBreaker) close(ctx ObjectContext) error {
+	b.state.State = CircuitClosed
+	b.state.Failures = 0
+	b.state.Probing = false
+	b.state.Generation++
+
+	waiters, err := circuitBreakerWaiters.Get(ctx)
+	if err != nil {
+		return err
+	}
+	for _, awakeableID := range waiters {
+		ResolveAwakeable(ctx, awakeableID, circuitPermit{})
+	}
+	circuitBreakerWaiters.Clear(ctx)
+	return nil
+}
+
+// grantProbe lets the first waiting call through as the probe of a half-open circuit.
+func (b *circuitBreaker) grantProbe(ctx ObjectContext) error {
+	waiters, err := circuitBreakerWaiters.Get(ctx)
+	if err != nil || len(waiters) == 0 {
+		return err
+	}
+	permit, ok := b.allow(ctx)
+	if !ok {
+		return nil
+	}
+	ResolveAwakeable(ctx, waiters[0], permit)
+	if len(waiters) == 1 {
+		circuitBreakerWaiters.Clear(ctx)
+	} else {
+		circuitBreakerWaiters.Set(ctx, waiters[1:])
+	}
+	return nil
+}
+
+func circuitOpenError(ctx ObjectSharedContext) TerminalError {
+	return ToTerminalError(fmt.Errorf("circuit breaker %s is open", Key(ctx)),
+		WithErrorCode(http.StatusServiceUnavailable), WithMetadata(CircuitBreakerMetadataKey, Key(ctx)))
+}
+
+// circuitBreakerAllow lets calls through a closed circuit without taking the exclusive lock of
+// the circuit breaker, and defers to circuitBreakerProbe for half-open circuits.
+func circuitBreakerAllow(ctx ObjectSharedContext, _ Void) (circuitPermit, error) {
+	state, err := circuitBreakerState.Get(ctx)
+	if err != nil {
+		return circuitPermit{}, err
+	}
+	switch {
+	case state == nil || state.State == CircuitClosed:
+		return circuitPermit{}, nil
+	case state.State == CircuitHalfOpen && !state.Probing:
+		return Object[circuitPermit](ctx, CircuitBreakerServiceName, Key(ctx), "Probe").Request(Void{})
+	default:
+		return circuitPermit{}, circuitOpenError(ctx)
+	}
+}
+
+func circuitBreakerProbe(ctx ObjectContext, _ Void) (circuitPermit, error) {
+	b, err := loadCircuitBreaker(ctx)
+	if err != nil {
+		return circuitPermit{}, err
+	}
+	permit, ok := b.allow(ctx)
+	if !ok {
+		return circuitPermit{}, circuitOpenError(ctx)
+	}
+	b.save(ctx)
+	return permit, nil
+}
+
+func circuitBreakerWait(ctx ObjectContext, req circuitWaitRequest) (Void, error) {
+	b, err := loadCircuitBreaker(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if permit, ok := b.allow(ctx); ok {
+		b.save(ctx)
+		ResolveAwakeable(ctx, req.AwakeableID, permit)
+		return Void{}, nil
+	}
+	waiters, err := circuitBreakerWaiters.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	circuitBreakerWaiters.Set(ctx, append(waiters, req.AwakeableID))
+	return Void{}, nil
+}
+
+func circuitBreakerRecord(ctx ObjectContext, outcome circuitOutcome) (Void, error) {
+	b, loadErr := loadCircuitBreaker(ctx)
+	if loadErr != nil {
+		return Void{}, loadErr
+	}
+	var err error
+	isProbe := outcome.Probe && b.state.State == CircuitHalfOpen && outcome.Generation == b.state.Generation
+
+	switch {
+	case outcome.Released && isProbe:
+		// The probe was cancelled, let another one through
+		b.state.Probing = false
+		err = b.grantProbe(ctx)
+	case outcome.Released:
+		return Void{}, nil
+	case isProbe && outcome.Success:
+		err = b.close(ctx)
+	case isProbe:
+		err = b.open(ctx)
+	case b.state.State != CircuitClosed:
+		// Outcome of a call let through before the circuit opened
+		return Void{}, nil
+	case outcome.Success:
+		b.state.Failures = 0
+	default:
+		b.state.Failures++
+		if b.state.Failures >= b.config.FailureThreshold {
+			err = b.open(ctx)
+		}
+	}
+	if err != nil {
+		return Void{}, err
+	}
+	b.save(ctx)
+	return Void{}, nil
+}
+
+// circuitBreakerTimeout makes an open circuit half-open, or lets another probe through when
+// the probe of a half-open circuit takes too long.
+func circuitBreakerTimeout(ctx ObjectContext, timeout circuitTimeout) (Void, error) {
+	b, err := loadCircuitBreaker(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if timeout.Generation != b.state.Generation {
+		return Void{}, nil
+	}
+	switch b.state.State {
+	case CircuitOpen:
+		b.state.State = CircuitHalfOpen
+	case CircuitHalfOpen:
+		b.state.Probing = false
+	default:
+		return Void{}, nil
+	}
+	if err := b.grantProbe(ctx); err != nil {
+		return Void{}, err
+	}
+	b.save(ctx)
+	return Void{}, nil
+}
+
+func circuitBreakerConfigure(ctx ObjectContext, config CircuitBreakerConfig) (Void, error) {
+	if config.FailureThreshold < 0 || config.OpenTimeout < 0 {
+		return Void{}, ToTerminalError(fmt.Errorf("circuit breaker %s: failure threshold and open timeout must not be negative", Key(ctx)), WithErrorCode(http.StatusBadRequest))
+	}
+	circuitBreakerConfig.Set(ctx, &config)
+	return Void{}, nil
+}
+
+func circuitBreakerReset(ctx ObjectContext, _ Void) (Void, error) {
+	b, err := loadCircuitBreaker(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if err := b.close(ctx); err != nil {
+		return Void{}, err
+	}
+	b.save(ctx)
+	return Void{}, nil
+}
+
+func circuitBreakerStatus(ctx ObjectSharedContext, _ Void) (CircuitBreakerStatus, error) {
+	config, err := circuitBreakerConfig.Get(ctx)
+	if err != nil {
+		return CircuitBreakerStatus{}, err
+	}
+	state, err := circuitBreakerState.Get(ctx)
+	if err != nil {
+		return CircuitBreakerStatus{}, err
+	}
+	waiters, err := circuitBreakerWaiters.Get(ctx)
+	if err != nil {
+		return CircuitBreakerStatus{}, err
+	}
+	status := CircuitBreakerStatus{State: CircuitClosed, Waiting: len(waiters)}
+	if config != nil {
+		status.Config = *config
+	}
+	status.Config = status.Config.withDefaults()
+	if state != nil {
+		status.State = state.State
+		status.Failures = state.Failures
+		status.OpenedAt = state.OpenedAt
+	}
+	return status, nil
+}
@@ -1,6 +1,8 @@
 package restate
 
 import (
+	"reflect"
+
 	"github.com/restatedev/sdk-go/encoding"
 	"github.com/restatedev/sdk-go/internal/options"
 )
@@ -9,6 +11,8 @@ import (
 // applies everywhere - value operations, handlers, calls, services and ingress - so
 // [WithCodec] works in every position. For handlers and calls (which have both an input
 // and an output) [WithInputCodec] / [WithOutputCodec] override a single direction.
+// Without any of them, the codec registered for the type of the value with
+// [encoding.Register] is used, falling back to JSON.
 
 type withCodec struct {
 	codec encoding.Codec
@@ -17,6 +21,9 @@ type withCodec struct {
 var (
 	_ options.GetOption                     = withCodec{}
 	_ options.SetOption                     = withCodec{}
+	_ options.StateKeyOption                = withCodec{}
+	_ options.StateCollectionOption         = withCodec{}
+	_ options.StateTTLOption                = withCodec{}
 	_ options.RunOption                     = withCodec{}
 	_ options.AwakeableOption               = withCodec{}
 	_ options.SignalOption                  = withCodec{}
@@ -33,8 +40,13 @@ var (
 	_ options.IngressInvocationHandleOption = withCodec{}
 )
 
-func (w withCodec) BeforeGet(opts *options.GetOptions)             { opts.Codec = w.codec }
-func (w withCodec) BeforeSet(opts *options.SetOptions)             { opts.Codec = w.codec }
+func (w withCodec) BeforeGet(opts *options.GetOptions)           { opts.Codec = w.codec }
+func (w withCodec) BeforeSet(opts *options.SetOptions)           { opts.Codec = w.codec }
+func (w withCodec) BeforeStateKey(opts *options.StateKeyOptions) { opts.Codec = w.codec }
+func (w withCodec) BeforeStateCollection(opts *options.StateCollectionOptions) {
+	opts.Codec = w.codec
+}
+func (w withCodec) BeforeStateTTL(opts *options.StateTTLOptions)   { opts.Codec = w.codec }
 func (w withCodec) BeforeRun(opts *options.RunOptions)             { opts.Codec = w.codec }
 func (w withCodec) BeforeAwakeable(opts *options.AwakeableOptions) { opts.Codec = w.codec }
 func (w withCodec) BeforeSignal(opts *options.SignalOptions)       { opts.Codec = w.codec }
@@ -133,3 +145,29 @@ var WithBinary = WithCodec(encoding.BinaryCodec)
 
 // WithJSON is an option to specify the use of [encoding.JSONCodec] for (de)serialisation
 var WithJSON = WithCodec(encoding.JSONCodec)
+
+// WithCBOR is an option to specify the use of [encoding.CBORCodec] for (de)serialisation
+var WithCBOR = WithCodec(encoding.CBORCodec)
+
+// WithMsgPack is an option to specify the use of [encoding.MsgPackCodec] for (de)serialisation
+var WithMsgPack = WithCodec(encoding.MsgPackCodec)
+
+// withRegisteredCodec prepends to opts the option selecting the codec registered for T with
+// [encoding.Register], if any, so that explicit codec options still take precedence.
+func withRegisteredCodec[T any, O any](opts []O) []O {
+	codec := encoding.RegisteredCodec(reflect.TypeFor[T]())
+	if codec == nil {
+		return opts
+	}
+	return append([]O{any(withCodec{codec}).(O)}, opts...)
+}
+
+// withRegisteredOutputCodec prepends to opts the option selecting the codec registered for
+// the output type O of a client, if any.
+func withRegisteredOutputCodec[O any](opts []options.ClientOption) []options.ClientOption {
+	codec := encoding.RegisteredCodec(reflect.TypeFor[O]())
+	if codec == nil {
+		return opts
+	}
+	return append([]options.ClientOption{withOutputCodec{codec}}, opts...)
+}
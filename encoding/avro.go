@@ -0,0 +1,243 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+)
+
+// AvroSchemaRegistry resolves the Avro schemas used by an [AvroCodec] from their id.
+type AvroSchemaRegistry interface {
+	// Schema returns the schema with the given id.
+	Schema(id uint32) (avro.Schema, error)
+}
+
+// AvroCodec returns a codec marshaling Go values, described by avro struct tags, with the
+// Avro schema schemaID of registry. Values are written in the wire format of the Confluent
+// schema registry, a zero byte followed by the schema id as a 4 bytes big endian integer, so
+// they can be shared with Kafka consumers. Values written with another schema of registry are
+// resolved to the schema schemaID when unmarshaled, following the Avro schema evolution rules.
+//
+// The Avro schema is converted to a JSON schema describing the payload for discovery.
+// In handlers, it uses a content type of avro/binary
+func AvroCodec(registry AvroSchemaRegistry, schemaID uint32) (Codec, error) {
+	schema, err := registry.Schema(schemaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Avro schema %d: %w", schemaID, err)
+	}
+	return &avroCodec{registry: registry, schemaID: schemaID, schema: schema}, nil
+}
+
+type avroCodec struct {
+	registry AvroSchemaRegistry
+	schemaID uint32
+	schema   avro.Schema
+	// resolved caches the schemas resolving the writer schema with the given id to schema.
+	resolved sync.Map // uint32 -> avro.Schema
+}
+
+const avroHeaderSize = 5
+
+func (a *avroCodec) ContentType() string { return "avro/binary" }
+func (a *avroCodec) JsonSchema(any) any  { return avroToJsonSchema(a.schema, map[string]bool{}) }
+
+// IsNonDeterministic is true when the schema has maps, which are written in the random
+// iteration order of Go maps.
+func (a *avroCodec) IsNonDeterministic() bool {
+	return avroHasMaps(a.schema, map[string]bool{})
+}
+
+func (a *avroCodec) Marshal(output any) ([]byte, error) {
+	data, err := avro.Marshal(a.schema, output)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, avroHeaderSize, avroHeaderSize+len(data))
+	binary.BigEndian.PutUint32(header[1:], a.schemaID)
+	return append(header, data...), nil
+}
+
+func (a *avroCodec) Unmarshal(data []byte, input any) error {
+	if len(data) < avroHeaderSize || data[0] != 0 {
+		return fmt.Errorf("invalid Avro header")
+	}
+	schema, err := a.readerSchema(binary.BigEndian.Uint32(data[1:]))
+	if err != nil {
+		return err
+	}
+	return avro.Unmarshal(schema, data[avroHeaderSize:], input)
+}
+
+func (a *avroCodec) readerSchema(writerID uint32) (avro.Schema, error) {
+	if writerID == a.schemaID {
+		return a.schema, nil
+	}
+	if schema, ok := a.resolved.Load(writerID); ok {
+		return schema.(avro.Schema), nil
+	}
+	writer, err := a.registry.Schema(writerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Avro schema %d: %w", writerID, err)
+	}
+	resolved, err := avro.NewSchemaCompatibility().Resolve(a.schema, writer)
+	if err != nil {
+		return nil, fmt.Errorf("Avro schema %d can't be read with schema %d: %w", writerID, a.schemaID, err)
+	}
+	a.resolved.Store(writerID, resolved)
+	return resolved, nil
+}
+
+// NewFileAvroSchemaRegistry returns an [AvroSchemaRegistry] with the schemas of the files
+// named <id>.avsc in dir, e.g. 1.avsc and 2.avsc for two versions of a schema. Every file
+// holds a standalone schema. It is meant for tests and local development.
+func NewFileAvroSchemaRegistry(dir string) (AvroSchemaRegistry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.avsc"))
+	if err != nil {
+		return nil, err
+	}
+	registry := fileAvroSchemaRegistry{}
+	for _, path := range paths {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), ".avsc"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Avro schema file %s is not named after its id", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := avro.ParseBytesWithCache(data, "", &avro.SchemaCache{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Avro schema file %s: %w", path, err)
+		}
+		registry[uint32(id)] = schema
+	}
+	return registry, nil
+}
+
+type fileAvroSchemaRegistry map[uint32]avro.Schema
+
+func (f fileAvroSchemaRegistry) Schema(id uint32) (avro.Schema, error) {
+	schema, ok := f[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown Avro schema %d", id)
+	}
+	return schema, nil
+}
+
+// avroToJsonSchema converts an Avro schema to the JSON schema of its values, as they would be
+// encoded by encoding/json. Recursive references are left unconstrained.
+func avroToJsonSchema(schema avro.Schema, inProgress map[string]bool) map[string]any {
+	switch schema := schema.(type) {
+	case *avro.RefSchema:
+		return avroToJsonSchema(schema.Schema(), inProgress)
+	case *avro.RecordSchema:
+		if inProgress[schema.FullName()] {
+			return map[string]any{}
+		}
+		inProgress[schema.FullName()] = true
+		defer delete(inProgress, schema.FullName())
+
+		properties := map[string]any{}
+		required := []string{}
+		for _, field := range schema.Fields() {
+			property := avroToJsonSchema(field.Type(), inProgress)
+			if field.Doc() != "" {
+				property["description"] = field.Doc()
+			}
+			if field.HasDefault() {
+				property["default"] = field.Default()
+			} else {
+				required = append(required, field.Name())
+			}
+			properties[field.Name()] = property
+		}
+		jsonSchema := map[string]any{
+			"type":       "object",
+			"title":      schema.Name(),
+			"properties": properties,
+			"required":   required,
+		}
+		if schema.Doc() != "" {
+			jsonSchema["description"] = schema.Doc()
+		}
+		return jsonSchema
+	case *avro.EnumSchema:
+		jsonSchema := map[string]any{"type": "string", "title": schema.Name(), "enum": schema.Symbols()}
+		if schema.Doc() != "" {
+			jsonSchema["description"] = schema.Doc()
+		}
+		return jsonSchema
+	case *avro.ArraySchema:
+		return map[string]any{"type": "array", "items": avroToJsonSchema(schema.Items(), inProgress)}
+	case *avro.MapSchema:
+		return map[string]any{"type": "object", "additionalProperties": avroToJsonSchema(schema.Values(), inProgress)}
+	case *avro.UnionSchema:
+		types := make([]any, 0, len(schema.Types()))
+		for _, typ := range schema.Types() {
+			types = append(types, avroToJsonSchema(typ, inProgress))
+		}
+		return map[string]any{"anyOf": types}
+	case *avro.FixedSchema:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case *avro.NullSchema:
+		return map[string]any{"type": "null"}
+	case *avro.PrimitiveSchema:
+		if logical := schema.Logical(); logical != nil {
+			switch logical.Type() {
+			case avro.UUID:
+				return map[string]any{"type": "string", "format": "uuid"}
+			case avro.Date:
+				return map[string]any{"type": "string", "format": "date"}
+			case avro.TimestampMillis, avro.TimestampMicros, avro.LocalTimestampMillis, avro.LocalTimestampMicros:
+				return map[string]any{"type": "string", "format": "date-time"}
+			}
+		}
+		switch schema.Type() {
+		case avro.String:
+			return map[string]any{"type": "string"}
+		case avro.Bytes:
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		case avro.Int, avro.Long:
+			return map[string]any{"type": "integer"}
+		case avro.Float, avro.Double:
+			return map[string]any{"type": "number"}
+		case avro.Boolean:
+			return map[string]any{"type": "boolean"}
+		}
+	}
+	return map[string]any{}
+}
+
+func avroHasMaps(schema avro.Schema, visited map[string]bool) bool {
+	switch schema := schema.(type) {
+	case *avro.RefSchema:
+		return avroHasMaps(schema.Schema(), visited)
+	case *avro.RecordSchema:
+		if visited[schema.FullName()] {
+			return false
+		}
+		visited[schema.FullName()] = true
+		for _, field := range schema.Fields() {
+			if avroHasMaps(field.Type(), visited) {
+				return true
+			}
+		}
+	case *avro.ArraySchema:
+		return avroHasMaps(schema.Items(), visited)
+	case *avro.MapSchema:
+		return true
+	case *avro.UnionSchema:
+		for _, typ := range schema.Types() {
+			if avroHasMaps(typ, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
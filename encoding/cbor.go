@@ -0,0 +1,56 @@
+package encoding
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBORCodec marshals and unmarshals any type supported by the fxamacker/cbor package, using
+// its core deterministic encoding (RFC 8949 section 4.2.1) so that round trips are stable.
+// Struct fields are named after their cbor tag, falling back to their json tag, so the JSON
+// schema advertised for handlers describes the CBOR data model of the payload.
+// In handlers, it uses a content type of application/cbor
+var CBORCodec Codec = cborCodec{
+	genJsonSchema: generateJsonSchema,
+	encMode:       mustCBOREncMode(),
+	decMode:       mustCBORDecMode(),
+}
+
+type cborCodec struct {
+	genJsonSchema func(v any) interface{}
+	encMode       cbor.EncMode
+	decMode       cbor.DecMode
+}
+
+func mustCBOREncMode() cbor.EncMode {
+	options := cbor.CoreDetEncOptions()
+	options.Time = cbor.TimeRFC3339Nano
+	encMode, err := options.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return encMode
+}
+
+func mustCBORDecMode() cbor.DecMode {
+	decMode, err := cbor.DecOptions{
+		// decode maps into any as they would be with JSON
+		DefaultMapType: reflect.TypeOf(map[string]any(nil)),
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return decMode
+}
+
+func (c cborCodec) ContentType() string  { return "application/cbor" }
+func (c cborCodec) JsonSchema(v any) any { return c.genJsonSchema(v) }
+
+func (c cborCodec) Unmarshal(data []byte, input any) (err error) {
+	return c.decMode.Unmarshal(data, input)
+}
+
+func (c cborCodec) Marshal(output any) ([]byte, error) {
+	return c.encMode.Marshal(output)
+}
@@ -0,0 +1,176 @@
+package encoding
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// claimCheckMagic prefixes the values written by a [ClaimCheck] codec that are either stored
+// in the blob store or look like a claim check, followed by the kind of value.
+var claimCheckMagic = []byte{0x00, 'r', 's', 'b'}
+
+const (
+	// claimCheckInline tags a value kept inline, which would otherwise be mistaken for a claim check.
+	claimCheckInline byte = iota
+	// claimCheckReference tags the key of a value stored in the blob store.
+	claimCheckReference
+)
+
+// DefaultClaimCheckThreshold is the size in bytes from which values are stored in the blob
+// store by a [ClaimCheck] codec.
+const DefaultClaimCheckThreshold = 256 * 1024
+
+// ErrBlobNotFound is returned by a [BlobStore] when no blob is stored with the given key.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores the values offloaded by a [ClaimCheck] codec. Blobs are keyed by the hash
+// of their content, so a blob stored with a key never changes: Put must succeed when the blob
+// already exists, which happens when a value is encoded again on retries and replays.
+type BlobStore interface {
+	// Put stores data with the given key.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data stored with the given key, or [ErrBlobNotFound].
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// ClaimCheck wraps inner so that the values it encodes that are at least
+// [DefaultClaimCheckThreshold] bytes long are written to store, and replaced by a reference
+// to the blob, keyed by the SHA-256 hash of the encoded value. Unmarshaling a reference fetches
+// the blob back from store and decodes it with inner. Smaller values are written as encoded
+// by inner.
+//
+// This keeps large payloads of calls, state and Run results under the message size limits of
+// Restate. Blobs are never deleted by the codec; expire them with the lifecycle policies of
+// the store. Like other codecs adding a binary header, ClaimCheck advertises no content type
+// or schema for handler payloads, so callers must use the same codec.
+func ClaimCheck(inner Codec, store BlobStore) Codec {
+	return ClaimCheckAbove(inner, store, DefaultClaimCheckThreshold)
+}
+
+// ClaimCheckAbove is like [ClaimCheck], storing the values that are at least threshold bytes
+// long in store.
+func ClaimCheckAbove(inner Codec, store BlobStore, threshold int) Codec {
+	return claimCheckCodec{inner: inner, store: store, threshold: threshold}
+}
+
+type claimCheckCodec struct {
+	inner     Codec
+	store     BlobStore
+	threshold int
+}
+
+func (c claimCheckCodec) IsNonDeterministic() bool {
+	return IsNonDeterministicSerialization(c.inner)
+}
+
+func (c claimCheckCodec) Marshal(output any) ([]byte, error) {
+	data, err := Marshal(c.inner, output)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < c.threshold {
+		if !bytes.HasPrefix(data, claimCheckMagic) {
+			return data, nil
+		}
+		return append(append(append([]byte{}, claimCheckMagic...), claimCheckInline), data...), nil
+	}
+	key := blobKey(data)
+	if err := c.store.Put(context.Background(), key, data); err != nil {
+		return nil, fmt.Errorf("failed to store blob %s: %w", key, err)
+	}
+	return append(append(append([]byte{}, claimCheckMagic...), claimCheckReference), key...), nil
+}
+
+func (c claimCheckCodec) Unmarshal(data []byte, input any) error {
+	if !bytes.HasPrefix(data, claimCheckMagic) {
+		return Unmarshal(c.inner, data, input)
+	}
+	if len(data) == len(claimCheckMagic) {
+		return fmt.Errorf("invalid claim check header")
+	}
+	payload := data[len(claimCheckMagic)+1:]
+	switch data[len(claimCheckMagic)] {
+	case claimCheckInline:
+	case claimCheckReference:
+		key := string(payload)
+		blob, err := c.store.Get(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("failed to fetch blob %s: %w", key, err)
+		}
+		if blobKey(blob) != key {
+			return fmt.Errorf("blob %s does not match its hash", key)
+		}
+		payload = blob
+	default:
+		return fmt.Errorf("invalid claim check header")
+	}
+	return Unmarshal(c.inner, payload, input)
+}
+
+func blobKey(data []byte) string {
+	hash := sha256.Sum256(data)
+	return "sha256-" + hex.EncodeToString(hash[:])
+}
+
+// NewFileBlobStore returns a [BlobStore] storing blobs as files in dir, which is created if
+// needed. It is meant for tests and local development.
+func NewFileBlobStore(dir string) (BlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return fileBlobStore{dir: dir}, nil
+}
+
+type fileBlobStore struct {
+	dir string
+}
+
+func (f fileBlobStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(f.dir, key), nil
+}
+
+func (f fileBlobStore) Put(_ context.Context, key string, data []byte) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	// write to a temporary file first, so that concurrent readers never see a partial blob
+	tmp, err := os.CreateTemp(f.dir, key+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f fileBlobStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
@@ -0,0 +1,192 @@
+package encoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressedMagic prefixes the values written by a [Compressed] codec, followed by the
+// [Compression] algorithm. No JSON or protobuf payload starts with a NUL byte, which lets
+// values written without compression be told apart.
+var compressedMagic = []byte{0x00, 'r', 's', 'z'}
+
+// DefaultCompressionThreshold is the size in bytes from which values are compressed by a
+// [Compressed] codec.
+const DefaultCompressionThreshold = 1024
+
+// Compression is a compression algorithm of a [Compressed] codec.
+type Compression byte
+
+const (
+	// NoCompression tags values left uncompressed.
+	NoCompression Compression = iota
+	// Gzip compresses values with gzip.
+	Gzip
+	// Zstd compresses values with Zstandard.
+	Zstd
+	// Snappy compresses values with the Snappy block format.
+	Snappy
+)
+
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Snappy:
+		return "snappy"
+	default:
+		return fmt.Sprintf("Compression(%d)", byte(c))
+	}
+}
+
+// Compressed wraps inner so that the values it encodes are compressed with algorithm when
+// they are at least [DefaultCompressionThreshold] bytes long. Smaller values are written as
+// encoded by inner. When unmarshaling, values compressed with any algorithm are decompressed,
+// and values without the compression header, e.g. written before adopting Compressed, are
+// decoded with inner as is.
+//
+// The header is binary, so Compressed is meant for state and journaled values such as Run
+// results; it advertises no content type or schema for handler payloads.
+func Compressed(inner Codec, algorithm Compression) Codec {
+	return CompressedAbove(inner, algorithm, DefaultCompressionThreshold)
+}
+
+// CompressedAbove is like [Compressed], compressing values that are at least threshold bytes
+// long.
+func CompressedAbove(inner Codec, algorithm Compression, threshold int) Codec {
+	if _, err := compress(algorithm, nil); err != nil {
+		panic(err)
+	}
+	return compressedCodec{inner: inner, algorithm: algorithm, threshold: threshold}
+}
+
+type compressedCodec struct {
+	inner     Codec
+	algorithm Compression
+	threshold int
+}
+
+var _ MigratingCodec = compressedCodec{}
+
+func (c compressedCodec) IsNonDeterministic() bool {
+	return IsNonDeterministicSerialization(c.inner)
+}
+
+func (c compressedCodec) Marshal(output any) ([]byte, error) {
+	data, err := Marshal(c.inner, output)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < c.threshold || c.algorithm == NoCompression {
+		if !bytes.HasPrefix(data, compressedMagic) {
+			return data, nil
+		}
+		// tag the value, so it's not mistaken for a compressed one
+		return append(append(append([]byte{}, compressedMagic...), byte(NoCompression)), data...), nil
+	}
+	compressed, err := compress(c.algorithm, data)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(append([]byte{}, compressedMagic...), byte(c.algorithm)), compressed...), nil
+}
+
+func (c compressedCodec) Unmarshal(data []byte, input any) error {
+	payload, err := decompressValue(data)
+	if err != nil {
+		return err
+	}
+	return Unmarshal(c.inner, payload, input)
+}
+
+// NeedsMigration reports whether the inner codec, if it is a [MigratingCodec], needs to
+// migrate the decompressed data.
+func (c compressedCodec) NeedsMigration(data []byte) bool {
+	migrating, ok := c.inner.(MigratingCodec)
+	if !ok {
+		return false
+	}
+	payload, err := decompressValue(data)
+	return err == nil && migrating.NeedsMigration(payload)
+}
+
+func decompressValue(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, compressedMagic) {
+		return data, nil
+	}
+	if len(data) == len(compressedMagic) {
+		return nil, fmt.Errorf("invalid compression header")
+	}
+	algorithm := Compression(data[len(compressedMagic)])
+	payload, err := decompress(algorithm, data[len(compressedMagic)+1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s value: %w", algorithm, err)
+	}
+	return payload, nil
+}
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) { return zstd.NewReader(nil) })
+)
+
+func compress(algorithm Compression, data []byte) ([]byte, error) {
+	switch algorithm {
+	case NoCompression:
+		return data, nil
+	case Gzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Zstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(data, nil), nil
+	case Snappy:
+		return s2.EncodeSnappy(nil, data), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm %s", algorithm)
+	}
+}
+
+func decompress(algorithm Compression, data []byte) ([]byte, error) {
+	switch algorithm {
+	case NoCompression:
+		return data, nil
+	case Gzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case Zstd:
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		return decoder.DecodeAll(data, nil)
+	case Snappy:
+		return s2.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm %s", algorithm)
+	}
+}
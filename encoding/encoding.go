@@ -356,6 +356,17 @@ func allocateProtoMessage(codecName string, input any) (proto.Message, error) {
 }
 
 func generateJsonSchema(v any) (schema interface{}) {
+	reflector := jsonschema.Reflector{
+		// Unfortunately we can't enable this due to a panic bug https://github.com/invopop/jsonschema/issues/163
+		// So we use expandSchema instead, which has the same effect but without the panic
+		// ExpandedStruct: true,
+	}
+	return reflectJsonSchema(v, func(v any) *jsonschema.Schema {
+		return util.ExpandSchema(reflector.Reflect(v))
+	})
+}
+
+func reflectJsonSchema(v any, reflectSchema func(v any) *jsonschema.Schema) (schema interface{}) {
 	defer func() {
 		if err := recover(); err != nil {
 			slog.Warn(`Error when trying to generate schema for the given type, you will see a generic schema in the UI Playground for this type.
@@ -370,10 +381,5 @@ If you can't fix the issue, disable json schema generation using encoding.JSONCo
 		}
 	}()
 
-	reflector := jsonschema.Reflector{
-		// Unfortunately we can't enable this due to a panic bug https://github.com/invopop/jsonschema/issues/163
-		// So we use expandSchema instead, which has the same effect but without the panic
-		// ExpandedStruct: true,
-	}
-	return util.ExpandSchema(reflector.Reflect(v))
+	return reflectSchema(v)
 }
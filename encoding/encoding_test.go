@@ -1,8 +1,13 @@
 package encoding
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -106,6 +111,8 @@ func TestIsNonDeterministicSerialization(t *testing.T) {
 		{"ProtoCodec", ProtoCodec, false},
 		{"ProtoJSONCodec", ProtoJSONCodec, true},
 		{"BinaryCodec", BinaryCodec, false},
+		{"CBORCodec", CBORCodec, false},
+		{"MsgPackCodec", MsgPackCodec, false},
 	}
 
 	for _, tt := range tests {
@@ -115,3 +122,510 @@ func TestIsNonDeterministicSerialization(t *testing.T) {
 		})
 	}
 }
+
+type order struct {
+	ID       string         `json:"id"`
+	Items    []string       `json:"items"`
+	Quantity map[string]int `json:"quantity"`
+	Note     *string        `json:"note,omitempty"`
+}
+
+func TestBinaryFormatCodecs(t *testing.T) {
+	tests := []struct {
+		name        string
+		codec       Codec
+		contentType string
+	}{
+		{"CBORCodec", CBORCodec, "application/cbor"},
+		{"MsgPackCodec", MsgPackCodec, "application/msgpack"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := order{ID: "o-1", Items: []string{"a", "b"}, Quantity: map[string]int{"z": 1, "a": 2, "m": 3}}
+			data, err := tt.codec.Marshal(input)
+			require.NoError(t, err)
+
+			var output order
+			require.NoError(t, tt.codec.Unmarshal(data, &output))
+			require.Equal(t, input, output)
+
+			// marshaling is deterministic
+			for i := 0; i < 10; i++ {
+				again, err := tt.codec.Marshal(output)
+				require.NoError(t, err)
+				require.Equal(t, data, again)
+			}
+
+			// untyped values decode as they would with JSON
+			var untyped any
+			require.NoError(t, tt.codec.Unmarshal(data, &untyped))
+			require.IsType(t, map[string]any{}, untyped)
+			require.Contains(t, untyped, "quantity")
+
+			payload := InputPayloadFor(tt.codec, order{})
+			require.Equal(t, tt.contentType, *payload.ContentType)
+			require.Equal(t, generateJsonSchema(order{}), payload.JsonSchema)
+		})
+	}
+}
+
+type personV2 struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+func TestVersioned(t *testing.T) {
+	// v0 (untagged): {"name":"Ada Lovelace"}, v1: {"fullName":"Ada Lovelace"}, v2: personV2
+	migrations := Migrations{
+		0: func(old []byte) ([]byte, error) {
+			var v0 struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(old, &v0); err != nil {
+				return nil, err
+			}
+			return json.Marshal(map[string]string{"fullName": v0.Name})
+		},
+		1: func(old []byte) ([]byte, error) {
+			var v1 struct {
+				FullName string `json:"fullName"`
+			}
+			if err := json.Unmarshal(old, &v1); err != nil {
+				return nil, err
+			}
+			first, last, _ := strings.Cut(v1.FullName, " ")
+			return json.Marshal(personV2{FirstName: first, LastName: last})
+		},
+	}
+	v1Codec := Versioned(JSONCodec, 1, nil)
+	v2Codec := Versioned(JSONCodec, 2, migrations)
+
+	expected := personV2{FirstName: "Ada", LastName: "Lovelace"}
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := Marshal(v2Codec, expected)
+		require.NoError(t, err)
+		version, err := SchemaVersion(data)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), version)
+		require.False(t, v2Codec.(MigratingCodec).NeedsMigration(data))
+
+		var actual personV2
+		require.NoError(t, Unmarshal(v2Codec, data, &actual))
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("untagged", func(t *testing.T) {
+		data := []byte(`{"name":"Ada Lovelace"}`)
+		require.True(t, v2Codec.(MigratingCodec).NeedsMigration(data))
+
+		var actual personV2
+		require.NoError(t, Unmarshal(v2Codec, data, &actual))
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("older version", func(t *testing.T) {
+		data, err := Marshal(v1Codec, map[string]string{"fullName": "Ada Lovelace"})
+		require.NoError(t, err)
+
+		var actual personV2
+		require.NoError(t, Unmarshal(v2Codec, data, &actual))
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("newer version", func(t *testing.T) {
+		data, err := Marshal(v2Codec, expected)
+		require.NoError(t, err)
+
+		var actual map[string]string
+		require.ErrorContains(t, Unmarshal(v1Codec, data, &actual), "newer than the current version 1")
+	})
+
+	t.Run("missing migration", func(t *testing.T) {
+		var actual map[string]string
+		require.ErrorContains(t, Unmarshal(v1Codec, []byte(`{}`), &actual), "no migration registered from schema version 0")
+	})
+}
+
+func TestCompressed(t *testing.T) {
+	large := map[string]string{"document": strings.Repeat("restate ", 512)}
+	small := map[string]string{"document": "restate"}
+
+	for _, algorithm := range []Compression{Gzip, Zstd, Snappy} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			codec := Compressed(JSONCodec, algorithm)
+
+			data, err := Marshal(codec, large)
+			require.NoError(t, err)
+			require.Less(t, len(data), 512)
+			again, err := Marshal(codec, large)
+			require.NoError(t, err)
+			require.Equal(t, data, again)
+
+			var actual map[string]string
+			require.NoError(t, Unmarshal(codec, data, &actual))
+			require.Equal(t, large, actual)
+
+			// values are decompressed whatever the configured algorithm
+			actual = nil
+			require.NoError(t, Unmarshal(Compressed(JSONCodec, Gzip), data, &actual))
+			require.Equal(t, large, actual)
+		})
+	}
+
+	t.Run("below threshold", func(t *testing.T) {
+		data, err := Marshal(Compressed(JSONCodec, Zstd), small)
+		require.NoError(t, err)
+		require.Equal(t, `{"document":"restate"}`, string(data))
+
+		data, err = Marshal(CompressedAbove(JSONCodec, Zstd, 1), small)
+		require.NoError(t, err)
+		require.NotEqual(t, `{"document":"restate"}`, string(data))
+	})
+
+	t.Run("uncompressed values", func(t *testing.T) {
+		var actual map[string]string
+		require.NoError(t, Unmarshal(Compressed(JSONCodec, Snappy), []byte(`{"document":"restate"}`), &actual))
+		require.Equal(t, small, actual)
+
+		// binary values looking like a compressed value are tagged
+		codec := Compressed(BinaryCodec, Snappy)
+		value := append(append([]byte{}, compressedMagic...), byte(Gzip), 1, 2, 3)
+		data, err := Marshal(codec, value)
+		require.NoError(t, err)
+		require.Len(t, data, len(value)+len(compressedMagic)+1)
+		var bytes []byte
+		require.NoError(t, Unmarshal(codec, data, &bytes))
+		require.Equal(t, value, bytes)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		data := append(append([]byte{}, compressedMagic...), byte(Zstd), 1, 2, 3)
+		var actual map[string]string
+		require.ErrorContains(t, Unmarshal(Compressed(JSONCodec, Zstd), data, &actual), "failed to decompress zstd value")
+	})
+
+	t.Run("versioned", func(t *testing.T) {
+		codec := CompressedAbove(Versioned(JSONCodec, 2, nil), Gzip, 1)
+		data, err := Marshal(CompressedAbove(Versioned(JSONCodec, 1, nil), Gzip, 1), small)
+		require.NoError(t, err)
+		require.True(t, codec.(MigratingCodec).NeedsMigration(data))
+		data, err = Marshal(codec, small)
+		require.NoError(t, err)
+		require.False(t, codec.(MigratingCodec).NeedsMigration(data))
+	})
+}
+
+func writeKeyFile(t *testing.T, current string, ids ...string) string {
+	keys := map[string]string{}
+	for _, id := range ids {
+		keys[id] = base64.StdEncoding.EncodeToString([]byte(strings.Repeat(id, 32)[:32]))
+	}
+	data, err := json.Marshal(map[string]any{"current": current, "keys": keys})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestEncrypted(t *testing.T) {
+	provider, err := NewFileKeyProvider(writeKeyFile(t, "a", "a"))
+	require.NoError(t, err)
+	codec := Encrypted(JSONCodec, provider)
+	secret := map[string]string{"ssn": "078-05-1120"}
+
+	data, err := Marshal(codec, secret)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "078-05-1120")
+	require.True(t, IsNonDeterministicSerialization(codec))
+	again, err := Marshal(codec, secret)
+	require.NoError(t, err)
+	require.NotEqual(t, data, again)
+
+	var actual map[string]string
+	require.NoError(t, Unmarshal(codec, data, &actual))
+	require.Equal(t, secret, actual)
+
+	t.Run("key rotation", func(t *testing.T) {
+		rotated, err := NewFileKeyProvider(writeKeyFile(t, "b", "a", "b"))
+		require.NoError(t, err)
+		rotatedCodec := Encrypted(JSONCodec, rotated)
+
+		var actual map[string]string
+		require.NoError(t, Unmarshal(rotatedCodec, data, &actual))
+		require.Equal(t, secret, actual)
+
+		newData, err := Marshal(rotatedCodec, secret)
+		require.NoError(t, err)
+		require.ErrorContains(t, Unmarshal(codec, newData, &actual), `failed to get encryption key b: unknown key "b"`)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		for _, i := range []int{len(encryptedMagic) + 2, len(data) - 1} {
+			tampered := append([]byte{}, data...)
+			tampered[i] ^= 0xff
+			require.Error(t, Unmarshal(codec, tampered, &actual))
+		}
+		require.Error(t, Unmarshal(codec, data[:len(data)-20], &actual))
+	})
+
+	t.Run("not encrypted", func(t *testing.T) {
+		require.ErrorContains(t, Unmarshal(codec, []byte(`{}`), &actual), "value is not encrypted")
+	})
+
+	t.Run("invalid key file", func(t *testing.T) {
+		_, err := NewFileKeyProvider(writeKeyFile(t, "c", "a"))
+		require.ErrorContains(t, err, `current key "c" is missing`)
+	})
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+type customer struct {
+	Name     string   `json:"name" jsonschema:"example=Ada"`
+	Tier     string   `json:"tier" jsonschema:"enum=free,enum=pro"`
+	Billing  address  `json:"billing"`
+	Shipping *address `json:"shipping,omitempty"`
+	Nickname *string  `json:"nickname,omitempty"`
+}
+
+func TestJSONCodecWithSchemaOptions(t *testing.T) {
+	codec := JSONCodecWithSchemaOptions(SchemaOptions{
+		Comments: map[string]string{
+			"github.com/restatedev/sdk-go/encoding.customer":      "A customer of the shop.",
+			"github.com/restatedev/sdk-go/encoding.customer.Name": "Full name of the customer.",
+		},
+		NullablePointers: true,
+	}).(CodecMetadata)
+
+	schema := codec.JsonSchema(customer{})
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	require.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema","$id":"https://github.com/restatedev/sdk-go/encoding/customer","$defs":{"address":{"properties":{"city":{"type":"string"}},"additionalProperties":false,"type":"object","required":["city"]},"customer":{"$ref":"#"}},"properties":{"name":{"type":"string","description":"Full name of the customer.","examples":["Ada"]},"tier":{"type":"string","enum":["free","pro"]},"billing":{"$ref":"#/$defs/address"},"shipping":{"oneOf":[{"$ref":"#/$defs/address"},{"type":"null"}]},"nickname":{"oneOf":[{"type":"string"},{"type":"null"}]}},"additionalProperties":false,"type":"object","required":["name","tier","billing"],"description":"A customer of the shop."}`, string(data))
+
+	// schemas are generated once per type
+	require.Same(t, schema, codec.JsonSchema(customer{}))
+
+	t.Run("self-contained definitions", func(t *testing.T) {
+		// The manifest has no shared definitions, so each payload defines the types it references
+		type order struct {
+			Customer customer `json:"customer"`
+			Delivery address  `json:"delivery"`
+		}
+		data, err := json.Marshal(codec.JsonSchema(order{}))
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"address":{"properties":{"city":{"type":"string"}}`)
+		require.NotContains(t, string(data), `"$ref":"https://`)
+	})
+
+	t.Run("inline definitions", func(t *testing.T) {
+		codec := JSONCodecWithSchemaOptions(SchemaOptions{InlineDefinitions: true}).(CodecMetadata)
+		data, err := json.Marshal(codec.JsonSchema(customer{}))
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "$defs")
+	})
+}
+
+func TestExtractGoComments(t *testing.T) {
+	comments, err := ExtractGoComments("github.com/restatedev/sdk-go/encoding", ".")
+	require.NoError(t, err)
+	require.Equal(t, "Compression is a compression algorithm of a Compressed codec.", comments["github.com/restatedev/sdk-go/encoding.Compression"])
+}
+
+type countingBlobStore struct {
+	BlobStore
+	puts int
+}
+
+func (c *countingBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	c.puts++
+	return c.BlobStore.Put(ctx, key, data)
+}
+
+func TestClaimCheck(t *testing.T) {
+	dir := t.TempDir()
+	fileStore, err := NewFileBlobStore(dir)
+	require.NoError(t, err)
+	store := &countingBlobStore{BlobStore: fileStore}
+	codec := ClaimCheckAbove(JSONCodec, store, 64)
+	document := map[string]string{"body": strings.Repeat("restate ", 64)}
+
+	data, err := Marshal(codec, document)
+	require.NoError(t, err)
+	require.Less(t, len(data), 100)
+
+	// the same value gets the same reference, and is stored once
+	again, err := Marshal(codec, document)
+	require.NoError(t, err)
+	require.Equal(t, data, again)
+	require.Equal(t, 2, store.puts)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	var actual map[string]string
+	require.NoError(t, Unmarshal(codec, data, &actual))
+	require.Equal(t, document, actual)
+
+	t.Run("below threshold", func(t *testing.T) {
+		data, err := Marshal(codec, map[string]string{"body": "restate"})
+		require.NoError(t, err)
+		require.Equal(t, `{"body":"restate"}`, string(data))
+
+		binaryCodec := ClaimCheckAbove(BinaryCodec, store, 64)
+		value := append(append([]byte{}, claimCheckMagic...), claimCheckReference, 'x')
+		data, err = Marshal(binaryCodec, value)
+		require.NoError(t, err)
+		var bytes []byte
+		require.NoError(t, Unmarshal(binaryCodec, data, &bytes))
+		require.Equal(t, value, bytes)
+	})
+
+	t.Run("missing blob", func(t *testing.T) {
+		emptyStore, err := NewFileBlobStore(t.TempDir())
+		require.NoError(t, err)
+		var actual map[string]string
+		err = Unmarshal(ClaimCheck(JSONCodec, emptyStore), data, &actual)
+		require.ErrorIs(t, err, ErrBlobNotFound)
+	})
+
+	t.Run("corrupted blob", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, entries[0].Name()), []byte(`{}`), 0o644))
+		var actual map[string]string
+		require.ErrorContains(t, Unmarshal(codec, data, &actual), "does not match its hash")
+	})
+}
+
+func TestStrictJSONCodec(t *testing.T) {
+	var actual personV2
+	require.NoError(t, StrictJSONCodec.Unmarshal([]byte(`{"firstName":"Ada","lastName":"Lovelace"} `), &actual))
+	require.Equal(t, personV2{FirstName: "Ada", LastName: "Lovelace"}, actual)
+
+	var invalidInput *InvalidInputError
+	err := StrictJSONCodec.Unmarshal([]byte(`{"firstName":"Ada","middleName":"King"}`), &actual)
+	require.ErrorAs(t, err, &invalidInput)
+	require.EqualError(t, err, `invalid input: json: unknown field "middleName"`)
+
+	err = StrictJSONCodec.Unmarshal([]byte(`{"firstName":"Ada"} {}`), &actual)
+	require.ErrorAs(t, err, &invalidInput)
+	require.EqualError(t, err, "invalid input: unexpected data after the JSON value")
+
+	// JSONCodec is lenient
+	require.NoError(t, JSONCodec.Unmarshal([]byte(`{"firstName":"Ada","middleName":"King"}`), &actual))
+}
+
+func TestCanonicalJSONCodec(t *testing.T) {
+	type document struct {
+		Zeta  string         `json:"zeta"`
+		Alpha map[string]any `json:"alpha"`
+		Raw   json.RawMessage
+	}
+	data, err := CanonicalJSONCodec.Marshal(document{
+		Zeta: "<tag> &   \"quoted\"\n\x01",
+		Alpha: map[string]any{
+			"\ufb33": 3, "€": 1, "\U0001F600": 2, "b": []any{1.5, 1e21, 1e-7, -0.0, 123456789012345678},
+		},
+		Raw: json.RawMessage(`{ "b" : 1.0, "a" : [ 1E2, 9007199254740993, 18446744073709551615, -123456789012345678901234567890, -0 ] }`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{"Raw":{"a":[100,9007199254740993,18446744073709551615,-123456789012345678901234567890,0],"b":1},"alpha":{"b":[1.5,1e+21,1e-7,0,123456789012345678],"€":1,"`+"\U0001F600"+`":2,"`+"\ufb33"+`":3},"zeta":"<tag> & `+" "+` \"quoted\"\n\u0001"}`, string(data))
+
+	canonical, err := Canonicalize(data)
+	require.NoError(t, err)
+	require.Equal(t, data, canonical)
+
+	var actual document
+	require.NoError(t, CanonicalJSONCodec.Unmarshal(data, &actual))
+	require.Equal(t, `{"a":[100,9007199254740993,18446744073709551615,-123456789012345678901234567890,0],"b":1}`, string(actual.Raw))
+}
+
+type userV1 struct {
+	ID   int64  `avro:"id"`
+	Name string `avro:"name"`
+}
+
+type userV2 struct {
+	ID    int64  `avro:"id"`
+	Name  string `avro:"name"`
+	Email string `avro:"email"`
+}
+
+func TestAvroCodec(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1.avsc"), []byte(`{
+		"type": "record", "name": "User", "namespace": "shop",
+		"fields": [{"name": "id", "type": "long"}, {"name": "name", "type": "string"}]
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2.avsc"), []byte(`{
+		"type": "record", "name": "User", "namespace": "shop", "doc": "A user of the shop.",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "name", "type": "string"},
+			{"name": "email", "type": "string", "default": "unknown", "doc": "Contact address."}
+		]
+	}`), 0o644))
+	registry, err := NewFileAvroSchemaRegistry(dir)
+	require.NoError(t, err)
+	v1Codec, err := AvroCodec(registry, 1)
+	require.NoError(t, err)
+	v2Codec, err := AvroCodec(registry, 2)
+	require.NoError(t, err)
+
+	data, err := v2Codec.Marshal(userV2{ID: 1, Name: "Ada", Email: "ada@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 2}, data[:5])
+	var actual userV2
+	require.NoError(t, v2Codec.Unmarshal(data, &actual))
+	require.Equal(t, userV2{ID: 1, Name: "Ada", Email: "ada@example.com"}, actual)
+	require.False(t, IsNonDeterministicSerialization(v2Codec))
+
+	t.Run("schema evolution", func(t *testing.T) {
+		data, err := v1Codec.Marshal(userV1{ID: 2, Name: "Grace"})
+		require.NoError(t, err)
+
+		var actual userV2
+		require.NoError(t, v2Codec.Unmarshal(data, &actual))
+		require.Equal(t, userV2{ID: 2, Name: "Grace", Email: "unknown"}, actual)
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		payload := InputPayloadFor(v2Codec, userV2{})
+		require.Equal(t, "avro/binary", *payload.ContentType)
+		schema, err := json.Marshal(payload.JsonSchema)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"type": "object", "title": "User", "description": "A user of the shop.",
+			"properties": {
+				"id": {"type": "integer"},
+				"name": {"type": "string"},
+				"email": {"type": "string", "default": "unknown", "description": "Contact address."}
+			},
+			"required": ["id", "name"]
+		}`, string(schema))
+	})
+
+	t.Run("unknown schema", func(t *testing.T) {
+		var actual userV2
+		require.ErrorContains(t, v2Codec.Unmarshal([]byte{0, 0, 0, 0, 3}, &actual), "unknown Avro schema 3")
+		_, err := AvroCodec(registry, 3)
+		require.Error(t, err)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	type registered struct{}
+	type unregistered struct{}
+	Register[registered](BinaryCodec)
+
+	require.Equal(t, BinaryCodec, RegisteredCodec(reflect.TypeFor[registered]()))
+	require.Nil(t, RegisteredCodec(reflect.TypeFor[unregistered]()))
+	require.Nil(t, RegisteredCodec(nil))
+
+	require.Equal(t, BinaryCodec, DefaultCodecFor(registered{}))
+	require.Equal(t, BinaryCodec, DefaultCodecFor(&registered{}))
+	require.Equal(t, "application/json", DefaultCodecFor(unregistered{}).(CodecMetadata).ContentType())
+	require.Equal(t, "application/json", DefaultCodecFor(nil).(CodecMetadata).ContentType())
+}
@@ -0,0 +1,224 @@
+package encoding
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// encryptedMagic prefixes the values written by an [Encrypted] codec, followed by the format
+// version, the id of the key encrypting the data key, the encrypted data key and the
+// encrypted value.
+var encryptedMagic = []byte{0x00, 'r', 's', 'e'}
+
+const (
+	encryptedFormatVersion = 1
+	// dataKeySize is the size of the AES-256 keys generated for every value.
+	dataKeySize = 32
+)
+
+// KeyProvider provides the key encryption keys of an [Encrypted] codec. Keys are AES keys
+// of 16, 24 or 32 bytes, identified by an id stored alongside the values they protect, so that
+// the current key can be rotated while the values encrypted with older keys remain readable.
+type KeyProvider interface {
+	// CurrentKey returns the key used to encrypt new values, and its id.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given id, used to decrypt values.
+	Key(id string) ([]byte, error)
+}
+
+// Encrypted wraps inner so that the values it encodes are encrypted with envelope encryption:
+// every value is encrypted with AES-GCM using a freshly generated data key, which is itself
+// encrypted with AES-GCM using the current key of keyProvider. The id of that key is stored in
+// the value, so values keep decrypting after the current key is rotated, as long as
+// keyProvider still provides the older keys.
+//
+// Encryption is randomized, so encrypted values are not deterministic. The codec can be used
+// for state, journaled values such as Run results, and handler payloads, which are advertised
+// as application/octet-stream: callers, e.g. the ingress client, must use the same codec.
+// Unmarshaling a value that was not encrypted fails.
+func Encrypted(inner Codec, keyProvider KeyProvider) Codec {
+	return encryptedCodec{inner: inner, keyProvider: keyProvider}
+}
+
+type encryptedCodec struct {
+	inner       Codec
+	keyProvider KeyProvider
+}
+
+var _ CodecMetadata = encryptedCodec{}
+
+func (e encryptedCodec) IsNonDeterministic() bool { return true }
+
+func (e encryptedCodec) ContentType() string { return "application/octet-stream" }
+func (e encryptedCodec) JsonSchema(any) any  { return nil }
+
+func (e encryptedCodec) Marshal(output any) ([]byte, error) {
+	data, err := Marshal(e.inner, output)
+	if err != nil {
+		return nil, err
+	}
+	keyID, key, err := e.keyProvider.CurrentKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the current encryption key: %w", err)
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	header := append([]byte{}, encryptedMagic...)
+	header = append(header, encryptedFormatVersion)
+	header = binary.AppendUvarint(header, uint64(len(keyID)))
+	header = append(header, keyID...)
+
+	// the header is authenticated along with the data key, so the key id can't be tampered with
+	encryptedDataKey, err := seal(key, dataKey, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt the data key with key %s: %w", keyID, err)
+	}
+	envelope := append(header, encryptedDataKey...)
+	ciphertext, err := seal(dataKey, data, envelope)
+	if err != nil {
+		return nil, err
+	}
+	return append(envelope, ciphertext...), nil
+}
+
+func (e encryptedCodec) Unmarshal(data []byte, input any) error {
+	if !bytes.HasPrefix(data, encryptedMagic) {
+		return fmt.Errorf("value is not encrypted")
+	}
+	rest := data[len(encryptedMagic):]
+	if len(rest) == 0 || rest[0] != encryptedFormatVersion {
+		return fmt.Errorf("unsupported encryption format")
+	}
+	rest = rest[1:]
+	keyIDLength, n := binary.Uvarint(rest)
+	if n <= 0 || keyIDLength > uint64(len(rest)-n) {
+		return fmt.Errorf("invalid encryption header")
+	}
+	keyID := string(rest[n : n+int(keyIDLength)])
+	header := data[:len(data)-len(rest)+n+int(keyIDLength)]
+
+	key, err := e.keyProvider.Key(keyID)
+	if err != nil {
+		return fmt.Errorf("failed to get encryption key %s: %w", keyID, err)
+	}
+	encryptedDataKeySize := gcmNonceSize + dataKeySize + gcmTagSize
+	if len(data) < len(header)+encryptedDataKeySize {
+		return fmt.Errorf("invalid encryption header")
+	}
+	envelope := data[:len(header)+encryptedDataKeySize]
+	dataKey, err := open(key, envelope[len(header):], header)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt the data key with key %s: %w", keyID, err)
+	}
+	payload, err := open(dataKey, data[len(envelope):], envelope)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return Unmarshal(e.inner, payload, input)
+}
+
+const (
+	gcmNonceSize = 12
+	gcmTagSize   = 16
+)
+
+// seal encrypts plaintext with AES-GCM, returning the random nonce followed by the ciphertext.
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcmNonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return aead.Open(nil, ciphertext[:gcmNonceSize], ciphertext[gcmNonceSize:], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewFileKeyProvider returns a [KeyProvider] reading its keys from the JSON file at path,
+// which lists the base64 encoded keys by id along with the id of the current key:
+//
+//	{
+//		"current": "2024-06",
+//		"keys": {
+//			"2024-01": "OXnBuzetM2mRgFoEJB6Djfv1MOg/WkCvZ9U2HAuKUDU=",
+//			"2024-06": "vGxgw7xlVC8MXMsr1OS5sAJPXXK2M2b7H8CrKbfz2RE="
+//		}
+//	}
+//
+// The file is read once. It is meant for tests and local development; production deployments
+// should implement KeyProvider on top of their key management service.
+func NewFileKeyProvider(path string) (KeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Current string            `json:"current"`
+		Keys    map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+	provider := fileKeyProvider{current: file.Current, keys: make(map[string][]byte, len(file.Keys))}
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key %s: %w", id, err)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", id, err)
+		}
+		provider.keys[id] = key
+	}
+	if _, ok := provider.keys[file.Current]; !ok {
+		return nil, fmt.Errorf("current key %q is missing from key file %s", file.Current, path)
+	}
+	return provider, nil
+}
+
+type fileKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (f fileKeyProvider) CurrentKey() (string, []byte, error) {
+	return f.current, f.keys[f.current], nil
+}
+
+func (f fileKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := f.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	return key, nil
+}
@@ -0,0 +1,47 @@
+package encoding
+
+import (
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+// MsgPackCodec marshals and unmarshals any type supported by the ugorji/go/codec package,
+// in canonical mode so that map keys are sorted and marshaling is deterministic. Struct fields
+// are named after their codec tag, falling back to their json tag, so the JSON schema
+// advertised for handlers describes the MessagePack data model of the payload.
+// In handlers, it uses a content type of application/msgpack
+var MsgPackCodec Codec = msgPackCodec{
+	genJsonSchema: generateJsonSchema,
+	handle:        newMsgPackHandle(),
+}
+
+type msgPackCodec struct {
+	genJsonSchema func(v any) interface{}
+	handle        *codec.MsgpackHandle
+}
+
+func newMsgPackHandle() *codec.MsgpackHandle {
+	handle := &codec.MsgpackHandle{
+		// use the str8 and bin formats of the current MessagePack spec
+		WriteExt: true,
+	}
+	handle.Canonical = true
+	handle.RawToString = true
+	handle.TypeInfos = codec.NewTypeInfos([]string{"codec", "json"})
+	// decode maps into any as they would be with JSON
+	handle.MapType = reflect.TypeOf(map[string]any(nil))
+	return handle
+}
+
+func (m msgPackCodec) ContentType() string  { return "application/msgpack" }
+func (m msgPackCodec) JsonSchema(v any) any { return m.genJsonSchema(v) }
+
+func (m msgPackCodec) Unmarshal(data []byte, input any) (err error) {
+	return codec.NewDecoderBytes(data, m.handle).Decode(input)
+}
+
+func (m msgPackCodec) Marshal(output any) (data []byte, err error) {
+	err = codec.NewEncoderBytes(&data, m.handle).Encode(output)
+	return data, err
+}
@@ -0,0 +1,52 @@
+package encoding
+
+import (
+	"reflect"
+	"sync"
+)
+
+// registeredCodecs maps the types registered with Register to their codec.
+var registeredCodecs sync.Map // reflect.Type -> Codec
+
+// Register sets codec as the codec of the values of type T. The SDK uses it for state, Run
+// results, awakeables, promises, signals, calls, handler inputs and outputs and the ingress
+// client whenever no codec is given explicitly with an option, such as restate.WithCodec on
+// the operation, the client, the handler or the service. Values of other types default to
+// [JSONCodec].
+//
+// Registering the codec of a type once, e.g. in an init function, ensures that all the
+// operations on its values agree on their encoding:
+//
+//	func init() {
+//		encoding.Register[*orderspb.Order](encoding.ProtoCodec)
+//	}
+func Register[T any](codec Codec) {
+	registeredCodecs.Store(reflect.TypeFor[T](), codec)
+}
+
+// RegisteredCodec returns the codec registered for the type t with [Register], or nil.
+func RegisteredCodec(t reflect.Type) Codec {
+	if t == nil {
+		return nil
+	}
+	if codec, ok := registeredCodecs.Load(t); ok {
+		return codec.(Codec)
+	}
+	return nil
+}
+
+// DefaultCodecFor returns the codec registered with [Register] for the type of v, or for the
+// type v points to when v is a pointer, e.g. the target of an unmarshal, falling back to
+// [JSONCodec].
+func DefaultCodecFor(v any) Codec {
+	t := reflect.TypeOf(v)
+	if codec := RegisteredCodec(t); codec != nil {
+		return codec
+	}
+	if t != nil && t.Kind() == reflect.Pointer {
+		if codec := RegisteredCodec(t.Elem()); codec != nil {
+			return codec
+		}
+	}
+	return JSONCodec
+}
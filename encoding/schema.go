@@ -0,0 +1,159 @@
+package encoding
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	"github.com/restatedev/sdk-go/encoding/internal/util"
+)
+
+// SchemaOptions configures the JSON schemas advertised by a codec created with
+// [JSONCodecWithSchemaOptions]. As with [JSONCodec], struct fields are described by their
+// json tags, and jsonschema tags add titles, descriptions, examples, enums, bounds and formats:
+//
+//	type Order struct {
+//		ID       string `json:"id" jsonschema:"title=Order id,example=ord_123"`
+//		Priority string `json:"priority" jsonschema:"enum=low,enum=high,default=low"`
+//	}
+type SchemaOptions struct {
+	// Comments maps fully qualified Go types and fields, e.g. example.com/shop.Order and
+	// example.com/shop.Order.ID, to the description of their schema, when it's not set by a
+	// jsonschema tag. Use [ExtractGoComments] at build time to generate it from the doc
+	// comments of the source code.
+	Comments map[string]string
+	// NullablePointers allows null for the fields of pointer type.
+	NullablePointers bool
+	// RequiredFromJSONSchemaTags only requires the fields with a jsonschema:"required" tag,
+	// rather than the ones without omitempty or omitzero json option.
+	RequiredFromJSONSchemaTags bool
+	// AllowAdditionalProperties allows properties that are not struct fields in objects.
+	AllowAdditionalProperties bool
+	// InlineDefinitions repeats the schema of the types referenced several times instead of
+	// defining them once in $defs.
+	InlineDefinitions bool
+	// Reflector, if set, is called to customize the reflector generating the schemas.
+	Reflector func(reflector *jsonschema.Reflector)
+}
+
+// JSONCodecWithSchemaOptions returns a codec that marshals like [JSONCodec] and advertises
+// JSON schemas generated according to options.
+//
+// Within the schema of a payload, the types referenced several times are defined once in
+// $defs, unless InlineDefinitions is set. Definitions are not shared across handlers: the
+// discovery manifest has no place for them, and the runtime and the UI resolve the references
+// of each payload schema on its own, so every schema defines the types it references.
+func JSONCodecWithSchemaOptions(options SchemaOptions) Codec {
+	generator := &schemaGenerator{options: options}
+	return jsonCodec{genJsonSchema: generator.generate}
+}
+
+// ExtractGoComments returns the doc comments of the types and fields declared in the Go
+// source files under path, keyed as expected by [SchemaOptions.Comments]. base is the import
+// path of the package in path. Source files are usually not available at runtime, so call it
+// from a program run by go generate, and embed its result in the binary:
+//
+//	//go:generate go run ./cmd/schemacomments
+//	//go:embed comments.json
+//	var comments []byte
+func ExtractGoComments(base string, path string) (map[string]string, error) {
+	reflector := jsonschema.Reflector{}
+	if err := reflector.AddGoComments(base, path); err != nil {
+		return nil, err
+	}
+	return reflector.CommentMap, nil
+}
+
+type schemaGenerator struct {
+	options SchemaOptions
+	schemas sync.Map // reflect.Type -> any
+}
+
+func (g *schemaGenerator) generate(v any) any {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
+	if schema, ok := g.schemas.Load(t); ok {
+		return schema
+	}
+	reflector := &jsonschema.Reflector{
+		CommentMap:                 g.options.Comments,
+		RequiredFromJSONSchemaTags: g.options.RequiredFromJSONSchemaTags,
+		AllowAdditionalProperties:  g.options.AllowAdditionalProperties,
+		DoNotReference:             g.options.InlineDefinitions,
+	}
+	if g.options.Reflector != nil {
+		g.options.Reflector(reflector)
+	}
+	schema := reflectJsonSchema(v, func(v any) *jsonschema.Schema {
+		schema := util.ExpandSchema(reflector.Reflect(v))
+		if g.options.NullablePointers {
+			markNullablePointers(reflector, schema, schema.Definitions, t, map[reflect.Type]bool{})
+		}
+		return schema
+	})
+	schema, _ = g.schemas.LoadOrStore(t, schema)
+	return schema
+}
+
+// markNullablePointers allows null for the pointer fields of the structs described by schema,
+// for the Go type t.
+func markNullablePointers(reflector *jsonschema.Reflector, schema *jsonschema.Schema, definitions jsonschema.Definitions, t reflect.Type, visited map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if schema == nil || visited[t] {
+		return
+	}
+	if name, ok := strings.CutPrefix(schema.Ref, "#/$defs/"); ok {
+		schema = definitions[name]
+		if schema == nil {
+			return
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		markNullablePointers(reflector, schema.Items, definitions, t.Elem(), visited)
+	case reflect.Map:
+		markNullablePointers(reflector, schema.AdditionalProperties, definitions, t.Elem(), visited)
+	case reflect.Struct:
+		if schema.Properties == nil {
+			return
+		}
+		visited[t] = true
+		for _, field := range reflect.VisibleFields(t) {
+			if !field.IsExported() || field.Anonymous && field.Tag.Get("json") == "" {
+				continue
+			}
+			name := fieldName(reflector, field)
+			property, ok := schema.Properties.Get(name)
+			if !ok {
+				continue
+			}
+			markNullablePointers(reflector, property, definitions, field.Type, visited)
+			if field.Type.Kind() == reflect.Pointer && len(property.OneOf) == 0 {
+				schema.Properties.Set(name, &jsonschema.Schema{
+					OneOf: []*jsonschema.Schema{property, {Type: "null"}},
+				})
+			}
+		}
+	}
+}
+
+func fieldName(reflector *jsonschema.Reflector, field reflect.StructField) string {
+	tag := reflector.FieldNameTag
+	if tag == "" {
+		tag = "json"
+	}
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	if name == "" {
+		name = field.Name
+		if reflector.KeyNamer != nil {
+			return reflector.KeyNamer(name)
+		}
+	}
+	return name
+}
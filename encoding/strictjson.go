@@ -0,0 +1,41 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// StrictJSONCodec marshals like [JSONCodec], and unmarshals rejecting the data that
+// [JSONCodec] accepts leniently: objects with fields unknown to the target struct, and
+// trailing data after the JSON value. Its errors are [InvalidInputError]s. Handlers whose input
+// fails to decode with it fail with a terminal error with code 400; when decoding other data
+// with it, wrap its errors in a terminal error to stop retries.
+// In handlers, it uses a content type of application/json
+var StrictJSONCodec Codec = strictJSONCodec{jsonCodec{genJsonSchema: generateJsonSchema}}
+
+// InvalidInputError is returned by codecs rejecting malformed data, such as
+// [StrictJSONCodec].
+type InvalidInputError struct {
+	Err error
+}
+
+func (e *InvalidInputError) Error() string { return "invalid input: " + e.Err.Error() }
+func (e *InvalidInputError) Unwrap() error { return e.Err }
+
+type strictJSONCodec struct {
+	jsonCodec
+}
+
+func (s strictJSONCodec) Unmarshal(data []byte, input any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(input); err != nil {
+		return &InvalidInputError{Err: err}
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return &InvalidInputError{Err: errors.New("unexpected data after the JSON value")}
+	}
+	return nil
+}
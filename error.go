@@ -1,9 +1,15 @@
 package restate
 
 import (
+	stderrors "errors"
 	"fmt"
+	"reflect"
+	"time"
 
+	"github.com/restatedev/sdk-go/encoding"
 	"github.com/restatedev/sdk-go/internal/errors"
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/restatedev/sdk-go/internal/stringmap"
 )
 
 // Code is a numeric status code for an error, matching HTTP status code semantics.
@@ -65,6 +71,104 @@ func AsTerminalError(err error) TerminalError {
 	return errors.AsTerminalError(err)
 }
 
+// TerminalErrorWithDetails builds a [TerminalError] carrying typed details, such as a domain
+// error struct, encoded into its metadata with codec, or with the codec registered with
+// [RegisterErrorDetails] if codec is nil. Callers recover the details with [ErrorDetails], or
+// errors.As if the details type implements error, whether they receive the error from a call
+// in a handler, from the ingress client or from a mock. It panics if details cannot be encoded
+// with the codec, like [Set] with a value that cannot be encoded.
+//
+// Like with [ToTerminalError], the code defaults to 500 unless set with [WithErrorCode].
+func TerminalErrorWithDetails(message string, details any, codec encoding.Codec, opts ...TerminalErrorOption) TerminalError {
+	metadata, err := errors.EncodeDetails(details, codec)
+	if err != nil {
+		panic(err)
+	}
+	return errors.NewTerminalError(message, append(opts, errors.WithMetadata(metadata))...)
+}
+
+// ErrorDetails decodes the details of an error built with [TerminalErrorWithDetails],
+// returning false if err carries no details of type T. Details are decoded with the codec
+// registered for T with [RegisterErrorDetails], JSON by default.
+func ErrorDetails[T any](err error) (T, bool) {
+	var details T
+	var withMetadata interface{ Metadata() stringmap.Map }
+	if !stderrors.As(err, &withMetadata) {
+		return details, false
+	}
+	ok, decodeErr := errors.DecodeDetails(withMetadata.Metadata(), &details)
+	return details, ok && decodeErr == nil
+}
+
+// RegisterErrorDetails sets the name and codec of the error details of type T. The name is
+// sent along with the details, and defaults to the package path and name of T; registering
+// a stable name lets the Go type be renamed or moved. Details types implementing error can
+// also be recovered with errors.As:
+//
+//	var insufficient InsufficientFunds
+//	if errors.As(err, &insufficient) { ... }
+func RegisterErrorDetails[T any](name string, codec encoding.Codec) {
+	errors.RegisterDetails(reflect.TypeFor[T](), name, codec)
+}
+
+// ErrorKind is the kind of failure an error is classified as by an error classifier.
+type ErrorKind = errors.Kind
+
+const (
+	// ErrorUnclassified leaves the error to the next classifier, or as is: it is retried,
+	// unless it is a [TerminalError].
+	ErrorUnclassified = errors.Unclassified
+	// ErrorTerminal fails the invocation or Run function without retrying, as a
+	// [TerminalError].
+	ErrorTerminal = errors.Terminal
+	// ErrorRetryable retries the invocation or Run function, as a [RetryableError].
+	ErrorRetryable = errors.Retryable
+)
+
+// Classification is the result of an error classifier, set with [WithErrorClassifier] or
+// the WithErrorClassifier method of the server. The zero Classification leaves the error
+// unclassified.
+type Classification = errors.Classification
+
+// TerminalClassification classifies an error as terminal, with the given code.
+func TerminalClassification(code Code) Classification {
+	return Classification{Kind: ErrorTerminal, Code: code}
+}
+
+// RetryableClassification classifies an error as retryable, with the given code.
+func RetryableClassification(code Code) Classification {
+	return Classification{Kind: ErrorRetryable, Code: code}
+}
+
+type withErrorClassifier struct {
+	classifier func(error) error
+}
+
+var _ options.ServiceDefinitionOption = withErrorClassifier{}
+
+func (w withErrorClassifier) BeforeServiceDefinition(opts *options.ServiceDefinitionOptions) {
+	opts.ErrorClassifier = w.classifier
+}
+
+// WithErrorClassifier maps the errors returned by the handlers and [Run] functions of a
+// service to terminal or retryable failures with a code, for example:
+//
+//	restate.WithErrorClassifier(func(err error) restate.Classification {
+//		switch {
+//		case errors.Is(err, sql.ErrNoRows):
+//			return restate.TerminalClassification(404)
+//		case errors.Is(err, context.DeadlineExceeded):
+//			return restate.RetryableClassification(503)
+//		}
+//		return restate.Classification{}
+//	})
+//
+// Errors already terminal or retryable are not classified. Errors left unclassified are
+// classified by the classifier of the server, if any.
+func WithErrorClassifier(classify func(error) Classification) withErrorClassifier {
+	return withErrorClassifier{errors.Classifier(classify)}
+}
+
 // RetryableError finishes an attempt with a non-terminal failure: the invocation (or a
 // Run closure) is retried rather than completed. It carries a [Code] and a message,
 // wraps the underlying error, and implements the error interface. Returning one from a
@@ -85,6 +189,33 @@ func ToRetryableError(err error, opts ...RetryableErrorOption) RetryableError {
 	return errors.ToRetryableError(err, opts...)
 }
 
+// RetryAfterOption sets the delay before the next attempt of a [RetryableError]. Pass it to
+// [ToRetryableError].
+type RetryAfterOption = errors.RetryAfterOption
+
+// WithRetryAfter sets the delay to wait before retrying a failed [Run] function, overriding
+// the retry intervals for that attempt, for example to honour the Retry-After header of a
+// rate limited HTTP call:
+//
+//	if res.StatusCode == http.StatusTooManyRequests {
+//		delay, _ := restate.ParseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+//		return nil, restate.ToRetryableError(errors.New("rate limited"),
+//			restate.WithErrorCode(429), restate.WithRetryAfter(delay))
+//	}
+//
+// The maximum attempts and duration set with [WithMaxRetryAttempts] and
+// [WithMaxRetryDuration] still apply. Handler failures are retried with the invocation retry
+// policy, which ignores the delay.
+func WithRetryAfter(d time.Duration) RetryAfterOption {
+	return errors.WithRetryAfter(d)
+}
+
+// ParseRetryAfter parses the value of a Retry-After HTTP header, either a number of seconds or
+// an HTTP date, into the delay to wait from now. It returns false if value is invalid.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	return errors.ParseRetryAfter(value, now)
+}
+
 // RetryableErrorf builds a [RetryableError] whose message is fmt.Sprintf(format, a...).
 // To attach a code, build the message with fmt.Errorf and pass it to [ToRetryableError]
 // with [WithErrorCode].
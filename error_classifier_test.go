@@ -0,0 +1,66 @@
+package restate_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	restateerrors "github.com/restatedev/sdk-go/internal/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorClassifier(t *testing.T) {
+	definition := restate.NewService("Orders", restate.WithErrorClassifier(func(err error) restate.Classification {
+		if errors.Is(err, sql.ErrNoRows) {
+			return restate.TerminalClassification(404)
+		}
+		return restate.Classification{}
+	}))
+	serverClassifier := restateerrors.Classifier(func(err error) restate.Classification {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return restate.RetryableClassification(503)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return restate.RetryableClassification(500)
+		}
+		return restate.Classification{}
+	})
+	classify := restateerrors.ChainClassifiers(definition.GetOptions().ErrorClassifier, serverClassifier)
+
+	t.Run("service classifier", func(t *testing.T) {
+		err := classify(fmt.Errorf("loading order: %w", sql.ErrNoRows))
+		require.True(t, restate.IsTerminalError(err))
+		require.Equal(t, restate.Code(404), restate.AsTerminalError(err).Code())
+		require.Equal(t, "loading order: sql: no rows in result set", err.Error())
+	})
+
+	t.Run("falls back to the server classifier", func(t *testing.T) {
+		err := classify(fmt.Errorf("calling payments: %w", context.DeadlineExceeded))
+		require.False(t, restate.IsTerminalError(err))
+		require.True(t, restate.IsRetryableError(err))
+	})
+
+	t.Run("unclassified", func(t *testing.T) {
+		original := errors.New("boom")
+		require.Same(t, original, classify(original))
+	})
+
+	t.Run("terminal errors are left unchanged", func(t *testing.T) {
+		original := restate.ToTerminalError(sql.ErrNoRows, restate.WithErrorCode(409))
+		require.Equal(t, restate.Code(409), restate.AsTerminalError(classify(original)).Code())
+	})
+
+	t.Run("default code", func(t *testing.T) {
+		classifyTerminal := restateerrors.Classifier(func(error) restate.Classification {
+			return restate.Classification{Kind: restate.ErrorTerminal}
+		})
+		require.Equal(t, restate.Code(500), restate.AsTerminalError(classifyTerminal(errors.New("boom"))).Code())
+	})
+
+	t.Run("no classifiers", func(t *testing.T) {
+		require.Nil(t, restateerrors.ChainClassifiers(nil, nil))
+	})
+}
@@ -3,6 +3,7 @@ package restate
 import (
 	"fmt"
 	"net/http"
+	"reflect"
 
 	"github.com/restatedev/sdk-go/internal/restatecontext"
 
@@ -55,10 +56,17 @@ func NewServiceHandler[I any, O any](fn ServiceHandlerFn[I, O], opts ...options.
 }
 
 func (h *serviceHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) ([]byte, error) {
+	inputCodec, outputCodec, err := handlerCodecs(ctx, &h.options)
+	if err != nil {
+		return nil, err
+	}
 	var input I
-	if err := encoding.Unmarshal(h.options.InputCodec, bytes, &input); err != nil {
+	if err := encoding.Unmarshal(inputCodec, bytes, &input); err != nil {
 		return nil, ToTerminalError(fmt.Errorf("request could not be decoded into handler input type: %v", err), WithErrorCode(http.StatusBadRequest))
 	}
+	if err := validateInput(h.options.Validator, &input); err != nil {
+		return nil, err
+	}
 
 	output, err := h.fn(
 		ctxWrapper{ctx},
@@ -68,7 +76,7 @@ func (h *serviceHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) ([
 		return nil, err
 	}
 
-	bytes, err = encoding.Marshal(h.options.OutputCodec, output)
+	bytes, err = encoding.Marshal(outputCodec, output)
 	if err != nil {
 		// we don't use a terminal error here as this is hot-fixable by changing the return type
 		return nil, fmt.Errorf("failed to serialize output: %w", err)
@@ -79,7 +87,7 @@ func (h *serviceHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) ([
 
 func (h *serviceHandler[I, O]) InputPayload() *encoding.InputPayload {
 	var i I
-	return encoding.InputPayloadFor(h.options.InputCodec, i)
+	return handlerInputPayload(&h.options, i)
 }
 
 func (h *serviceHandler[I, O]) OutputPayload() *encoding.OutputPayload {
@@ -95,6 +103,10 @@ func (h *serviceHandler[I, O]) GetOptions() *options.HandlerOptions {
 	return &h.options
 }
 
+func (h *serviceHandler[I, O]) registeredCodecs() (encoding.Codec, encoding.Codec) {
+	return encoding.RegisteredCodec(reflect.TypeFor[I]()), encoding.RegisteredCodec(reflect.TypeFor[O]())
+}
+
 type objectHandler[I any, O any] struct {
 	// only one of exclusiveFn or sharedFn should be set, as indicated by handlerType
 	exclusiveFn ObjectHandlerFn[I, O]
@@ -146,13 +158,20 @@ func (o ctxWrapper) workflow()        {}
 func (o ctxWrapper) runWorkflow()     {}
 
 func (h *objectHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) ([]byte, error) {
+	ctx = withStateTTL(ctx, &h.options)
+	inputCodec, outputCodec, err := handlerCodecs(ctx, &h.options)
+	if err != nil {
+		return nil, err
+	}
 	var input I
-	if err := encoding.Unmarshal(h.options.InputCodec, bytes, &input); err != nil {
+	if err := encoding.Unmarshal(inputCodec, bytes, &input); err != nil {
 		return nil, ToTerminalError(fmt.Errorf("request could not be decoded into handler input type: %v", err), WithErrorCode(http.StatusBadRequest))
 	}
+	if err := validateInput(h.options.Validator, &input); err != nil {
+		return nil, err
+	}
 
 	var output O
-	var err error
 	switch h.handlerType {
 	case internal.ServiceHandlerType_EXCLUSIVE:
 		output, err = h.exclusiveFn(
@@ -169,7 +188,7 @@ func (h *objectHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) ([]
 		return nil, err
 	}
 
-	bytes, err = encoding.Marshal(h.options.OutputCodec, output)
+	bytes, err = encoding.Marshal(outputCodec, output)
 	if err != nil {
 		// we don't use a terminal error here as this is hot-fixable by changing the return type
 		return nil, fmt.Errorf("failed to serialize output: %w", err)
@@ -180,7 +199,7 @@ func (h *objectHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) ([]
 
 func (h *objectHandler[I, O]) InputPayload() *encoding.InputPayload {
 	var i I
-	return encoding.InputPayloadFor(h.options.InputCodec, i)
+	return handlerInputPayload(&h.options, i)
 }
 
 func (h *objectHandler[I, O]) OutputPayload() *encoding.OutputPayload {
@@ -192,6 +211,10 @@ func (h *objectHandler[I, O]) GetOptions() *options.HandlerOptions {
 	return &h.options
 }
 
+func (h *objectHandler[I, O]) registeredCodecs() (encoding.Codec, encoding.Codec) {
+	return encoding.RegisteredCodec(reflect.TypeFor[I]()), encoding.RegisteredCodec(reflect.TypeFor[O]())
+}
+
 func (h *objectHandler[I, O]) HandlerType() *internal.ServiceHandlerType {
 	return &h.handlerType
 }
@@ -235,13 +258,19 @@ func NewWorkflowSharedHandler[I any, O any](fn WorkflowSharedHandlerFn[I, O], op
 }
 
 func (h *workflowHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) ([]byte, error) {
+	inputCodec, outputCodec, err := handlerCodecs(ctx, &h.options)
+	if err != nil {
+		return nil, err
+	}
 	var input I
-	if err := encoding.Unmarshal(h.options.InputCodec, bytes, &input); err != nil {
+	if err := encoding.Unmarshal(inputCodec, bytes, &input); err != nil {
 		return nil, ToTerminalError(fmt.Errorf("request could not be decoded into handler input type: %v", err), WithErrorCode(http.StatusBadRequest))
 	}
+	if err := validateInput(h.options.Validator, &input); err != nil {
+		return nil, err
+	}
 
 	var output O
-	var err error
 	switch h.handlerType {
 	case internal.ServiceHandlerType_WORKFLOW:
 		output, err = h.workflowFn(
@@ -258,7 +287,7 @@ func (h *workflowHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) (
 		return nil, err
 	}
 
-	bytes, err = encoding.Marshal(h.options.OutputCodec, output)
+	bytes, err = encoding.Marshal(outputCodec, output)
 	if err != nil {
 		// we don't use a terminal error here as this is hot-fixable by changing the return type
 		return nil, fmt.Errorf("failed to serialize output: %w", err)
@@ -269,7 +298,7 @@ func (h *workflowHandler[I, O]) Call(ctx restatecontext.Context, bytes []byte) (
 
 func (h *workflowHandler[I, O]) InputPayload() *encoding.InputPayload {
 	var i I
-	return encoding.InputPayloadFor(h.options.InputCodec, i)
+	return handlerInputPayload(&h.options, i)
 }
 
 func (h *workflowHandler[I, O]) OutputPayload() *encoding.OutputPayload {
@@ -281,6 +310,10 @@ func (h *workflowHandler[I, O]) GetOptions() *options.HandlerOptions {
 	return &h.options
 }
 
+func (h *workflowHandler[I, O]) registeredCodecs() (encoding.Codec, encoding.Codec) {
+	return encoding.RegisteredCodec(reflect.TypeFor[I]()), encoding.RegisteredCodec(reflect.TypeFor[O]())
+}
+
 func (h *workflowHandler[I, O]) HandlerType() *internal.ServiceHandlerType {
 	return &h.handlerType
 }
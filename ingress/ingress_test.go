@@ -432,3 +432,37 @@ func newIngressClient(baseUri string) *ingress.Client {
 		ingress.WithHttpClient(http.DefaultClient),
 		ingress.WithAuthKey(authKey))
 }
+
+type insufficientFunds struct {
+	Missing int `json:"missing"`
+}
+
+func (e insufficientFunds) Error() string {
+	return fmt.Sprintf("missing %d", e.Missing)
+}
+
+func TestRequestErrorDetails(t *testing.T) {
+	m := newMockIngressServer()
+	defer m.Close()
+
+	failure := restate.TerminalErrorWithDetails("insufficient funds", insufficientFunds{Missing: 42}, nil, restate.WithErrorCode(409))
+	m.failureStatus = 409
+	m.failure = map[string]any{
+		"code":     409,
+		"message":  failure.Message(),
+		"metadata": failure.Metadata(),
+	}
+
+	c := newIngressClient(m.URL)
+	_, err := ingress.Service[restate.Void, restate.Void](c, myService, myHandler).
+		Request(context.Background(), restate.Void{})
+	require.Error(t, err)
+
+	details, ok := restate.ErrorDetails[insufficientFunds](err)
+	require.True(t, ok)
+	require.Equal(t, insufficientFunds{Missing: 42}, details)
+
+	var asDetails insufficientFunds
+	require.ErrorAs(t, err, &asDetails)
+	require.Equal(t, 42, asDetails.Missing)
+}
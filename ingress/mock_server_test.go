@@ -22,6 +22,9 @@ type mockIngressServer struct {
 	headers map[string]string
 	body    []byte
 	query   map[string]string
+	// failure, when set, is returned as a JSON error response with failureStatus
+	failure       any
+	failureStatus int
 }
 
 func newMockIngressServer() *mockIngressServer {
@@ -46,7 +49,10 @@ func (m *mockIngressServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		m.query[k] = v[0]
 	}
 
-	if strings.HasSuffix(m.path, "/send") || (strings.HasPrefix(m.path, "/restate/scope/") && strings.Contains(m.path, "/send/")) {
+	if m.failure != nil {
+		w.WriteHeader(m.failureStatus)
+		json.NewEncoder(w).Encode(m.failure)
+	} else if strings.HasSuffix(m.path, "/send") || (strings.HasPrefix(m.path, "/restate/scope/") && strings.Contains(m.path, "/send/")) {
 		inv := ingress.Invocation{
 			Id:     "inv_1",
 			Status: "Accepted",
@@ -0,0 +1,72 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	start := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC) // A Wednesday
+
+	tests := []struct {
+		expr string
+		next []time.Time
+	}{
+		{expr: "* * * * *", next: []time.Time{
+			time.Date(2024, 1, 31, 10, 18, 0, 0, time.UTC),
+			time.Date(2024, 1, 31, 10, 19, 0, 0, time.UTC),
+		}},
+		{expr: "*/15 * * * *", next: []time.Time{
+			time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC),
+			time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC),
+			time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC),
+		}},
+		{expr: "0 9-17/4 * * mon-fri", next: []time.Time{
+			time.Date(2024, 1, 31, 13, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+		}},
+		{expr: "@monthly", next: []time.Time{
+			time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{expr: "30 6 29 feb *", next: []time.Time{
+			time.Date(2024, 2, 29, 6, 30, 0, 0, time.UTC),
+			time.Date(2028, 2, 29, 6, 30, 0, 0, time.UTC),
+		}},
+		// Day of month or Sunday
+		{expr: "0 0 1 * 7", next: []time.Time{
+			time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC),
+		}},
+		{expr: "0 0 30 2 *", next: []time.Time{{}}},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := Parse(test.expr)
+			require.NoError(t, err)
+			at := start
+			for _, expected := range test.next {
+				at = schedule.Next(at)
+				require.Equal(t, expected, at)
+			}
+		})
+	}
+}
+
+func TestNextLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	schedule, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), schedule.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, loc)).UTC())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		_, err := Parse(expr)
+		require.Error(t, err, expr)
+	}
+}
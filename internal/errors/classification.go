@@ -0,0 +1,65 @@
+package errors
+
+// Kind is the kind of failure an error is classified as.
+type Kind int
+
+const (
+	// Unclassified leaves the error as is: it is retried, unless it is a TerminalError.
+	Unclassified Kind = iota
+	// Terminal fails the invocation or Run function without retrying.
+	Terminal
+	// Retryable retries the invocation or Run function.
+	Retryable
+)
+
+// Classification is the result of an error classifier for an error.
+type Classification struct {
+	Kind Kind
+	// Code of the failure, DefaultCode if zero.
+	Code Code
+}
+
+// Classifier converts errors not already terminal or retryable into the TerminalError or
+// RetryableError the classify function decides, leaving the others unchanged.
+func Classifier(classify func(error) Classification) func(error) error {
+	return func(err error) error {
+		if err == nil || IsTerminalError(err) || IsRetryableError(err) {
+			return err
+		}
+		classification := classify(err)
+		code := classification.Code
+		if code == 0 {
+			code = DefaultCode
+		}
+		switch classification.Kind {
+		case Terminal:
+			return ToTerminalError(err, WithCode(code))
+		case Retryable:
+			return ToRetryableError(err, WithCode(code))
+		default:
+			return err
+		}
+	}
+}
+
+// ChainClassifiers returns a classifier applying the given classifiers in order, until one
+// classifies the error. Nil classifiers are skipped, and nil is returned if all are nil.
+func ChainClassifiers(classifiers ...func(error) error) func(error) error {
+	var chain []func(error) error
+	for _, classifier := range classifiers {
+		if classifier != nil {
+			chain = append(chain, classifier)
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return func(err error) error {
+		for _, classifier := range chain {
+			if err = classifier(err); IsTerminalError(err) || IsRetryableError(err) {
+				return err
+			}
+		}
+		return err
+	}
+}
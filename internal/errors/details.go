@@ -0,0 +1,118 @@
+package errors
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal/stringmap"
+)
+
+// Metadata keys under which the typed details of a TerminalError are stored.
+const (
+	// DetailsMetadataKey holds the encoded details.
+	DetailsMetadataKey = "restate.errorDetails"
+	// DetailsTypeMetadataKey holds the name of the details type, as registered with
+	// RegisterDetails or derived from the Go type.
+	DetailsTypeMetadataKey = "restate.errorDetailsType"
+	// DetailsEncodingMetadataKey is set to "base64" when the encoded details are not valid
+	// UTF-8, and stored base64 encoded.
+	DetailsEncodingMetadataKey = "restate.errorDetailsEncoding"
+)
+
+type detailsType struct {
+	name  string
+	codec encoding.Codec
+}
+
+// detailsTypes maps registered details types to their detailsType.
+var detailsTypes sync.Map
+
+// RegisterDetails sets the name and codec of a details type, used when encoding details of
+// that type and when decoding them with errors.As.
+func RegisterDetails(typ reflect.Type, name string, codec encoding.Codec) {
+	if codec == nil {
+		codec = encoding.JSONCodec
+	}
+	detailsTypes.Store(typ, detailsType{name: name, codec: codec})
+}
+
+func detailsTypeOf(typ reflect.Type) detailsType {
+	if registered, ok := detailsTypes.Load(typ); ok {
+		return registered.(detailsType)
+	}
+	name := typ.String()
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		name = typ.PkgPath() + "." + typ.Name()
+	}
+	return detailsType{name: name, codec: encoding.JSONCodec}
+}
+
+// EncodeDetails returns the metadata carrying details encoded with codec, or with the codec
+// registered for its type if codec is nil.
+func EncodeDetails(details any, codec encoding.Codec) (map[string]string, error) {
+	typ := detailsTypeOf(reflect.TypeOf(details))
+	if codec == nil {
+		codec = typ.codec
+	}
+	data, err := encoding.Marshal(codec, details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode error details: %w", err)
+	}
+	metadata := map[string]string{DetailsTypeMetadataKey: typ.name}
+	if utf8.Valid(data) {
+		metadata[DetailsMetadataKey] = string(data)
+	} else {
+		metadata[DetailsMetadataKey] = base64.StdEncoding.EncodeToString(data)
+		metadata[DetailsEncodingMetadataKey] = "base64"
+	}
+	return metadata, nil
+}
+
+// DecodeDetails decodes the details carried by metadata into target, a pointer, with the
+// codec registered for the target type. It returns false if metadata carries no details, or
+// details of another type.
+func DecodeDetails(metadata stringmap.Map, target any) (bool, error) {
+	if metadata == nil {
+		return false, nil
+	}
+	encoded := metadata.Get(DetailsMetadataKey)
+	if encoded == "" {
+		return false, nil
+	}
+	typ := detailsTypeOf(reflect.TypeOf(target).Elem())
+	if name := metadata.Get(DetailsTypeMetadataKey); name != "" && name != typ.name {
+		return false, nil
+	}
+
+	data := []byte(encoded)
+	if metadata.Get(DetailsEncodingMetadataKey) == "base64" {
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return false, fmt.Errorf("failed to decode error details: %w", err)
+		}
+	}
+	if err := encoding.Unmarshal(typ.codec, data, target); err != nil {
+		return false, fmt.Errorf("failed to decode error details: %w", err)
+	}
+	return true, nil
+}
+
+// AsDetails implements the As method of errors carrying metadata, so that errors.As
+// decodes their details into targets of the matching type.
+func AsDetails(metadata stringmap.Map, target any) bool {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return false
+	}
+	// Decode into a fresh value, so that target is left untouched on failure
+	decoded := reflect.New(value.Type().Elem())
+	if ok, err := DecodeDetails(metadata, decoded.Interface()); !ok || err != nil {
+		return false
+	}
+	value.Elem().Set(decoded.Elem())
+	return true
+}
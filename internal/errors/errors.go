@@ -9,6 +9,9 @@ type Code uint16
 // DefaultCode is the code assigned to an error when none is provided.
 const DefaultCode Code = 500
 
+// CancelledCode is the code of the errors returned when an invocation is cancelled.
+const CancelledCode Code = 409
+
 // CodeOption sets the status code. It is shared: it satisfies both TerminalErrorOption
 // and RetryableErrorOption, so the same option works for either error type.
 type CodeOption struct{ code Code }
@@ -18,3 +21,7 @@ func (o CodeOption) applyRetryable(e *retryableError) { e.code = o.code }
 
 // WithCode sets the status code on a terminal or retryable error.
 func WithCode(code Code) CodeOption { return CodeOption{code: code} }
+
+// CircuitBreakerFailureMetadataKey marks the terminal errors of Run functions guarded by a
+// circuit breaker which failed with a retryable error. Its value is the name of the breaker.
+const CircuitBreakerFailureMetadataKey = "restate.circuitBreakerFailure"
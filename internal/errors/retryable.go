@@ -3,6 +3,10 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // RetryableError finishes an attempt with a non-terminal failure: the invocation (or a
@@ -14,14 +18,18 @@ type RetryableError interface {
 	Code() Code
 	// Message returns the error message.
 	Message() string
+	// RetryAfter returns the delay to wait before the next attempt, or zero to use the
+	// retry policy.
+	RetryAfter() time.Duration
 
 	// To seal the interface
 	retryableError()
 }
 
 type retryableError struct {
-	code Code
-	err  error
+	code       Code
+	err        error
+	retryAfter time.Duration
 }
 
 var _ RetryableError = (*retryableError)(nil)
@@ -32,9 +40,36 @@ func (e *retryableError) Message() string { return e.err.Error() }
 func (e *retryableError) Unwrap() error   { return e.err }
 func (e *retryableError) retryableError() {}
 
+func (e *retryableError) RetryAfter() time.Duration { return e.retryAfter }
+
 // RetryableErrorOption customizes a RetryableError at construction time.
 type RetryableErrorOption interface{ applyRetryable(*retryableError) }
 
+// RetryAfterOption sets the delay before the next attempt of a RetryableError.
+type RetryAfterOption struct{ d time.Duration }
+
+func (o RetryAfterOption) applyRetryable(e *retryableError) { e.retryAfter = max(0, o.d) }
+
+// WithRetryAfter sets the delay to wait before the next attempt, overriding the retry policy.
+func WithRetryAfter(d time.Duration) RetryAfterOption { return RetryAfterOption{d: d} }
+
+// ParseRetryAfter parses the value of a Retry-After HTTP header, either a number of seconds or
+// an HTTP date, into the delay to wait from now. It returns false if value is invalid.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(0, date.Sub(now)), true
+}
+
 // NewRetryableError wraps err as a RetryableError, defaulting the code to DefaultCode
 // unless overridden by an option.
 func NewRetryableError(err error, opts ...RetryableErrorOption) RetryableError {
@@ -27,6 +27,8 @@ type terminalError struct {
 	code     Code
 	message  string
 	metadata map[string]string
+	// cancellation is set only on the errors built by NewCancellationError
+	cancellation bool
 }
 
 var _ TerminalError = (*terminalError)(nil)
@@ -37,6 +39,9 @@ func (e *terminalError) Message() string         { return e.message }
 func (e *terminalError) Metadata() stringmap.Map { return stringmap.New(e.metadata) }
 func (e *terminalError) terminalError()          {}
 
+// As lets errors.As decode the details of the error into a target of the details type.
+func (e *terminalError) As(target any) bool { return AsDetails(e.Metadata(), target) }
+
 // TerminalErrorOption customizes a TerminalError at construction time.
 type TerminalErrorOption interface{ applyTerminal(*terminalError) }
 
@@ -120,3 +125,30 @@ func ToTerminalError(err error, opts ...TerminalErrorOption) TerminalError {
 	}
 	return NewTerminalError(err.Error(), opts...)
 }
+
+// WithoutMetadata returns err without the metadata keys, or err itself if it has none of
+// them.
+func WithoutMetadata(err TerminalError, keys ...string) TerminalError {
+	metadata := err.Metadata().ToMap()
+	for _, key := range keys {
+		delete(metadata, key)
+	}
+	if len(metadata) == len(err.Metadata().ToMap()) {
+		return err
+	}
+	return NewTerminalError(err.Message(), WithCode(err.Code()), WithMetadata(metadata))
+}
+
+// NewCancellationError returns the TerminalError of the operations interrupted because the
+// invocation was cancelled.
+func NewCancellationError() TerminalError {
+	return &terminalError{code: CancelledCode, message: "cancelled", cancellation: true}
+}
+
+// IsCancellation reports whether err is, or wraps, a TerminalError built by
+// NewCancellationError. Terminal errors with code 409 built otherwise, for example by user
+// code or from the failures received from the runtime, are not cancellations.
+func IsCancellation(err error) bool {
+	var t *terminalError
+	return errors.As(err, &t) && t.cancellation
+}
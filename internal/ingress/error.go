@@ -1,10 +1,16 @@
 package ingress
 
+import (
+	"github.com/restatedev/sdk-go/internal/errors"
+	"github.com/restatedev/sdk-go/internal/stringmap"
+)
+
 type restateError struct {
-	Message     string `json:"message"`
-	Code        int    `json:"code,omitempty"`
-	Description string `json:"description,omitempty"`
-	Stacktrace  string `json:"stacktrace,omitempty"`
+	Message       string            `json:"message"`
+	Code          int               `json:"code,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Stacktrace    string            `json:"stacktrace,omitempty"`
+	ErrorMetadata map[string]string `json:"metadata,omitempty"`
 }
 
 type GenericError struct {
@@ -23,6 +29,17 @@ func (e restateError) Error() string {
 	return e.Message
 }
 
+// Metadata returns the metadata of the failure, which carries the error details of terminal
+// errors.
+func (e restateError) Metadata() stringmap.Map {
+	return stringmap.New(e.ErrorMetadata)
+}
+
+// As lets errors.As decode the error details of the failure.
+func (e restateError) As(target any) bool {
+	return errors.AsDetails(e.Metadata(), target)
+}
+
 func newGenericError(err *restateError) *GenericError {
 	return &GenericError{
 		restateError: err,
@@ -91,13 +91,13 @@ func (c *Client) do(ctx context.Context, httpMethod, path string, requestData an
 		inputCodec = c.clientOpts.Codec
 	}
 	if inputCodec == nil {
-		inputCodec = encoding.JSONCodec
+		inputCodec = encoding.DefaultCodecFor(requestData)
 	}
 	if outputCodec == nil {
 		outputCodec = c.clientOpts.Codec
 	}
 	if outputCodec == nil {
-		outputCodec = encoding.JSONCodec
+		outputCodec = encoding.DefaultCodecFor(responseData)
 	}
 
 	// marshal the request data if provided
@@ -182,7 +182,7 @@ func (c *Client) do(ctx context.Context, httpMethod, path string, requestData an
 		case http.StatusInternalServerError:
 			return newGenericError(&rerr)
 		}
-		return fmt.Errorf("request failed with unexpected status %s: %s", res.Status, bodyStr)
+		return fmt.Errorf("request failed with unexpected status %s: %w", res.Status, newGenericError(&rerr))
 	}
 
 	if responseData != nil {
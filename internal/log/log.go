@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
@@ -34,10 +35,35 @@ func Stringer[T fmt.Stringer](key string, value T) slog.Attr {
 	return slog.Any(key, stringerValue[T]{value})
 }
 
+// Error returns the attribute logging err, as its redacted representation if it is, or wraps,
+// a [logging.Redactable].
 func Error(err error) slog.Attr {
+	var r logging.Redactable
+	if errors.As(err, &r) {
+		return slog.Any("err", r.Redacted())
+	}
 	return slog.String("err", err.Error())
 }
 
+type payloadValue struct{ inner any }
+
+func (p payloadValue) LogValue() slog.Value {
+	return slog.AnyValue(p.inner)
+}
+
+// Payload returns the attribute logging value, which holds user data such as inputs and outputs,
+// or contains it such as the messages of the errors returned by handlers. It is dropped by the
+// handlers returned by WithoutPayloads.
+func Payload(key string, value any) slog.Attr {
+	return slog.Any(key, payloadValue{value})
+}
+
+// PayloadError is like Error, for the errors whose message may contain payloads.
+func PayloadError(err error) slog.Attr {
+	attr := Error(err)
+	return Payload(attr.Key, attr.Value)
+}
+
 type contextInjectingHandler struct {
 	logContext *atomic.Pointer[logging.LogContext]
 	dropReplay bool
@@ -63,11 +89,11 @@ func (d *contextInjectingHandler) Enabled(ctx context.Context, l slog.Level) boo
 }
 
 func (d *contextInjectingHandler) Handle(ctx context.Context, record slog.Record) error {
-	return d.inner.Handle(logging.WithLogContext(ctx, d.logContext.Load()), record)
+	return d.inner.Handle(logging.WithLogContext(ctx, d.logContext.Load()), mapRecordAttrs(record, redactAttr))
 }
 
 func (d *contextInjectingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &contextInjectingHandler{d.logContext, d.dropReplay, d.inner.WithAttrs(attrs)}
+	return &contextInjectingHandler{d.logContext, d.dropReplay, d.inner.WithAttrs(mapAttrs(attrs, redactAttr))}
 }
 
 func (d *contextInjectingHandler) WithGroup(name string) slog.Handler {
@@ -75,3 +101,89 @@ func (d *contextInjectingHandler) WithGroup(name string) slog.Handler {
 }
 
 var _ slog.Handler = &contextInjectingHandler{}
+
+// redactAttr replaces the values of a by their representation which is safe to log, see
+// [logging.Redact].
+func redactAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindAny:
+		return slog.Any(a.Key, logging.Redact(a.Value.Any()))
+	case slog.KindLogValuer:
+		switch valuer := a.Value.LogValuer().(type) {
+		case payloadValue:
+			// keep the payload marker for the handlers returned by WithoutPayloads
+			return slog.Any(a.Key, payloadValue{redactAttr(slog.Any(a.Key, valuer.inner)).Value})
+		case logging.Redactable:
+			return slog.Any(a.Key, valuer.Redacted())
+		}
+		return redactAttr(slog.Attr{Key: a.Key, Value: a.Value.Resolve()})
+	case slog.KindGroup:
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(mapAttrs(a.Value.Group(), redactAttr)...)}
+	}
+	return a
+}
+
+type payloadDroppingHandler struct {
+	inner slog.Handler
+}
+
+// WithoutPayloads returns a handler replacing by [logging.RedactedPlaceholder] the payloads
+// logged with Payload, and the attributes holding bytes such as json.RawMessage, before passing
+// the records to inner.
+func WithoutPayloads(inner slog.Handler) slog.Handler {
+	return &payloadDroppingHandler{inner}
+}
+
+func (d *payloadDroppingHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return d.inner.Enabled(ctx, l)
+}
+
+func (d *payloadDroppingHandler) Handle(ctx context.Context, record slog.Record) error {
+	return d.inner.Handle(ctx, mapRecordAttrs(record, dropPayload))
+}
+
+func (d *payloadDroppingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &payloadDroppingHandler{d.inner.WithAttrs(mapAttrs(attrs, dropPayload))}
+}
+
+func (d *payloadDroppingHandler) WithGroup(name string) slog.Handler {
+	return &payloadDroppingHandler{d.inner.WithGroup(name)}
+}
+
+var _ slog.Handler = &payloadDroppingHandler{}
+
+func dropPayload(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindAny:
+		if v := reflect.ValueOf(a.Value.Any()); v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return slog.String(a.Key, logging.RedactedPlaceholder)
+		}
+	case slog.KindLogValuer:
+		if _, ok := a.Value.LogValuer().(payloadValue); ok {
+			return slog.String(a.Key, logging.RedactedPlaceholder)
+		}
+	case slog.KindGroup:
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(mapAttrs(a.Value.Group(), dropPayload)...)}
+	}
+	return a
+}
+
+func mapAttrs(attrs []slog.Attr, f func(slog.Attr) slog.Attr) []slog.Attr {
+	mapped := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		mapped[i] = f(attr)
+	}
+	return mapped
+}
+
+func mapRecordAttrs(record slog.Record, f func(slog.Attr) slog.Attr) slog.Record {
+	if record.NumAttrs() == 0 {
+		return record
+	}
+	mapped := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		mapped.AddAttrs(f(attr))
+		return true
+	})
+	return mapped
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password" restate:"redact"`
+}
+
+type secretError struct{}
+
+func (secretError) Error() string { return "invalid password hunter2" }
+func (secretError) Redacted() any { return "invalid password" }
+
+func TestRedactingHandlers(t *testing.T) {
+	logged := func(dropPayloads bool, log func(*slog.Logger)) map[string]any {
+		var buf bytes.Buffer
+		var handler slog.Handler = slog.NewJSONHandler(&buf, nil)
+		if dropPayloads {
+			handler = WithoutPayloads(handler)
+		}
+		log(slog.New(NewRestateContextHandler(handler)))
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		return record
+	}
+
+	record := logged(false, func(logger *slog.Logger) {
+		logger.With("login", credentials{User: "ada", Password: "hunter2"}).
+			Info("message", Error(secretError{}), slog.Group("group", "body", json.RawMessage(`{"a":1}`)))
+	})
+	require.Equal(t, map[string]any{"user": "ada", "password": "[REDACTED]"}, record["login"])
+	require.Equal(t, "invalid password", record["err"])
+	require.Equal(t, map[string]any{"body": map[string]any{"a": float64(1)}}, record["group"])
+
+	record = logged(false, func(logger *slog.Logger) {
+		logger.Info("message", PayloadError(errors.New("input 42")))
+	})
+	require.Equal(t, "input 42", record["err"])
+
+	record = logged(true, func(logger *slog.Logger) {
+		logger.Info("message", slog.Group("group", "body", json.RawMessage(`{"a":1}`)), PayloadError(secretError{}), slog.String("kept", "value"))
+	})
+	require.Equal(t, map[string]any{"body": "[REDACTED]"}, record["group"])
+	require.Equal(t, "[REDACTED]", record["err"])
+	require.Equal(t, "value", record["kept"])
+}
@@ -97,6 +97,32 @@ type SetOption interface {
 	BeforeSet(*SetOptions)
 }
 
+type StateKeyOptions struct {
+	Codec encoding.Codec
+}
+
+type StateKeyOption interface {
+	BeforeStateKey(*StateKeyOptions)
+}
+
+type StateTTLOptions struct {
+	Codec          encoding.Codec
+	ExpiryCallback string
+}
+
+type StateTTLOption interface {
+	BeforeStateTTL(*StateTTLOptions)
+}
+
+type StateCollectionOptions struct {
+	Codec     encoding.Codec
+	ChunkSize int
+}
+
+type StateCollectionOption interface {
+	BeforeStateCollection(*StateCollectionOptions)
+}
+
 type ClientOptions struct {
 	InputCodec  encoding.Codec
 	OutputCodec encoding.Codec
@@ -186,6 +212,21 @@ type RunOptions struct {
 	// If any of the other retry options are set, this will be set by default to 2 seconds.
 	MaxRetryInterval *time.Duration
 
+	// RetryIf reports whether a failure of the Run function is retried. Failures it rejects
+	// fail the Run function as terminal errors.
+	RetryIf func(error) bool
+
+	// RetryJitter randomizes the retry intervals by up to this fraction, in [0, 1].
+	RetryJitter float64
+
+	// CircuitBreaker is the name of the circuit breaker guarding the Run function. Its
+	// retryable failures are then returned as terminal errors, and retried by the caller.
+	CircuitBreaker string
+
+	// CircuitBreakerWait waits for an open circuit breaker to let the call through, rather
+	// than failing fast.
+	CircuitBreakerWait bool
+
 	// Name used for observability.
 	Name string
 
@@ -197,6 +238,15 @@ type RunOption interface {
 	BeforeRun(*RunOptions)
 }
 
+type LockOptions struct {
+	// LeaseTimeout after which a permit is released even if its holder is still running.
+	LeaseTimeout time.Duration
+}
+
+type LockOption interface {
+	BeforeLock(*LockOptions)
+}
+
 type AttachOptions struct {
 	Codec encoding.Codec
 }
@@ -205,9 +255,18 @@ type AttachOption interface {
 	BeforeAttach(*AttachOptions)
 }
 
+// Validator validates decoded handler inputs, returning an error describing why the input is
+// invalid.
+type Validator interface {
+	Validate(input any) error
+}
+
 type HandlerOptions struct {
-	InputCodec            encoding.Codec
-	OutputCodec           encoding.Codec
+	InputCodec  encoding.Codec
+	OutputCodec encoding.Codec
+	// AcceptedCodecs are the codecs negotiated with the request headers, in addition to
+	// InputCodec and OutputCodec.
+	AcceptedCodecs        []encoding.Codec
 	Metadata              map[string]string
 	Documentation         string
 	AbortTimeout          *time.Duration
@@ -218,6 +277,9 @@ type HandlerOptions struct {
 	JournalRetention      *time.Duration
 	WorkflowRetention     *time.Duration
 	InvocationRetryPolicy *InvocationRetryPolicy
+	Validator             Validator
+	// StateTTL is set on the handlers of the virtual objects whose state entries can expire.
+	StateTTL bool
 }
 
 type HandlerOption interface {
@@ -236,6 +298,12 @@ type ServiceDefinitionOptions struct {
 	IngressPrivate        *bool
 	JournalRetention      *time.Duration
 	InvocationRetryPolicy *InvocationRetryPolicy
+	DefaultValidator      Validator
+	// ErrorClassifier converts the errors returned by handlers and Run functions into
+	// terminal or retryable errors, returning the others unchanged.
+	ErrorClassifier func(error) error
+	// StateTTL is set on the virtual objects whose state entries can expire.
+	StateTTL bool
 }
 
 type ServiceDefinitionOption interface {
@@ -10,14 +10,21 @@ import (
 	"github.com/restatedev/sdk-go/internal/statemachine"
 )
 
-var CancelledFailureValue = func() statemachine.Value {
+// cancelledFailure is the failure of the operations interrupted by the cancel signal.
+var cancelledFailure = func() *pbinternal.TerminalFailure {
 	failure := pbinternal.TerminalFailure{}
-	failure.SetCode(409)
+	failure.SetCode(uint32(errors.CancelledCode))
 	failure.SetMessage("Cancelled")
-	return statemachine.ValueFailure{Failure: &failure}
+	return &failure
 }()
 
+// CancelledFailureValue is the result of the operations interrupted by the cancel signal.
+var CancelledFailureValue statemachine.Value = statemachine.ValueFailure{Failure: cancelledFailure}
+
 func errorFromFailure(failure statemachine.ValueFailure) errors.TerminalError {
+	if failure.Failure == cancelledFailure {
+		return errors.NewCancellationError()
+	}
 	return errors.NewTerminalError(
 		failure.Failure.GetMessage(),
 		errors.WithCode(errors.Code(failure.Failure.GetCode())),
@@ -87,12 +94,27 @@ func (a *asyncResult) pollProgressAndLoadValue() statemachine.Value {
 }
 
 func (restateCtx *ctx) pollProgress(handles []uint32) bool {
+	if restateCtx.cancelPending && restateCtx.shielded == 0 {
+		// Deliver the cancel signal received in a shielded scope
+		restateCtx.cancelPending = false
+		return true
+	}
+
 	// Pump output once
 	if err := takeOutputAndWriteOut(restateCtx, restateCtx.stateMachine, restateCtx.stream); err != nil {
 		panic(err)
 	}
 
 	for {
+		if restateCtx.cancelPending {
+			// Once it received the cancel signal, the state machine keeps reporting it instead of
+			// the progress of the awaited operations, so the shielded scope tracks it itself
+			if restateCtx.pollShieldedProgress(handles) {
+				return false
+			}
+			continue
+		}
+
 		progressResult, err := restateCtx.stateMachine.DoProgress(restateCtx, handles)
 		if err != nil {
 			panic(err)
@@ -101,62 +123,122 @@ func (restateCtx *ctx) pollProgress(handles []uint32) bool {
 			return false
 		}
 		if _, ok := progressResult.(statemachine.DoProgressWaitingExternalProgress); ok {
-			// Write out awaiting on message
-			if err := takeOutputAndWriteOut(restateCtx, restateCtx.stateMachine, restateCtx.stream); err != nil {
-				panic(err)
-			}
-
-			// Either wait for at least one read or for run proposals
-			select {
-			case readRes, ok := <-restateCtx.readChan:
-				if !ok {
-					// Got EOF, notify and break
-					if err = restateCtx.stateMachine.NotifyInputClosed(restateCtx); err != nil {
-						panic(err)
-					}
-					break
-				}
-				if err = restateCtx.stateMachine.NotifyInput(restateCtx, readRes.buf[0:readRes.nRead]); err != nil {
-					panic(err)
-				}
-				BufPool.Put(readRes.buf)
-				break
-			case proposal := <-restateCtx.runClosureCompletions:
-				// Propose completion
-				if err := restateCtx.stateMachine.ProposeRunCompletion(restateCtx, proposal); err != nil {
-					panic(err)
-				}
-
-				// Pump output once. This is needed for the run completion to be effectively written
-				if err := takeOutputAndWriteOut(restateCtx, restateCtx.stateMachine, restateCtx.stream); err != nil {
-					panic(err)
-				}
-				break
-			case <-restateCtx.Done():
-				panic(restateCtx.Err())
-			}
+			restateCtx.waitExternalProgress()
 		}
 		if _, ok := progressResult.(statemachine.DoProgressCancelSignalReceived); ok {
+			restateCtx.cancelReceived = true
+
 			// Pump output once. This is needed for cancel commands to be effectively written
 			if err := takeOutputAndWriteOut(restateCtx, restateCtx.stateMachine, restateCtx.stream); err != nil {
 				panic(err)
 			}
 
+			if restateCtx.shielded > 0 {
+				restateCtx.cancelPending = true
+				continue
+			}
 			return true
 		}
 		if executeRun, ok := progressResult.(statemachine.DoProgressExecuteRun); ok {
-			closure, ok := restateCtx.runClosures[executeRun.Handle]
-			if !ok {
-				panic(fmt.Sprintf("Need to run a Run closure with coreHandle %d, but it doesn't exist. This is an SDK bug.", executeRun.Handle))
-			}
+			restateCtx.executeRun(executeRun.Handle)
+		}
+	}
+}
+
+// pollShieldedProgress makes progress on the awaited handles once the cancel signal was received
+// in a shielded scope, executing their Run closures and waiting for input, and returns whether
+// any of them completed.
+func (restateCtx *ctx) pollShieldedProgress(handles []uint32) bool {
+	progressResult, err := restateCtx.stateMachine.DoProgress(restateCtx, handles)
+	if err != nil {
+		panic(err)
+	}
+	switch progressResult := progressResult.(type) {
+	case statemachine.DoProgressAnyCompleted:
+		return true
+	case statemachine.DoProgressExecuteRun:
+		restateCtx.executeRun(progressResult.Handle)
+		return false
+	case statemachine.DoProgressWaitingExternalProgress:
+		restateCtx.waitExternalProgress()
+		return false
+	}
 
-			// Delete this closure from the running list
-			delete(restateCtx.runClosures, executeRun.Handle)
+	// The state machine reported the cancel signal again, after processing the notifications
+	// received so far: look at the awaited handles directly
+	for _, handle := range handles {
+		completed, err := restateCtx.stateMachine.IsCompleted(restateCtx, handle)
+		if err != nil {
+			panic(err)
+		}
+		if completed {
+			return true
+		}
+		if _, ok := restateCtx.runClosures[handle]; ok {
+			restateCtx.executeRun(handle)
+		}
+	}
+	if restateCtx.inputClosed {
+		// No more input can complete the awaited handles
+		panic(statemachine.SuspensionError{})
+	}
+	restateCtx.waitExternalProgress()
+	return false
+}
 
-			// Run closure in a separate goroutine, proposing the result to runClosureCompletions
-			go func(runClosureCompletions chan *pbinternal.VmProposeRunCompletionParameters, closure func() *pbinternal.VmProposeRunCompletionParameters) {
-				runClosureCompletions <- closure()
-			}(restateCtx.runClosureCompletions, closure)
+// waitExternalProgress writes out the output of the state machine, then waits to either read
+// input or receive the completion of a Run closure, and notifies the state machine of it.
+func (restateCtx *ctx) waitExternalProgress() {
+	// Write out awaiting on message
+	if err := takeOutputAndWriteOut(restateCtx, restateCtx.stateMachine, restateCtx.stream); err != nil {
+		panic(err)
+	}
+
+	// Either wait for at least one read or for run proposals
+	select {
+	case readRes, ok := <-restateCtx.readChan:
+		if !ok {
+			// Got EOF, notify
+			if !restateCtx.inputClosed {
+				restateCtx.inputClosed = true
+				if err := restateCtx.stateMachine.NotifyInputClosed(restateCtx); err != nil {
+					panic(err)
+				}
+			}
+			return
+		}
+		if err := restateCtx.stateMachine.NotifyInput(restateCtx, readRes.buf[0:readRes.nRead]); err != nil {
+			panic(err)
+		}
+		BufPool.Put(readRes.buf)
+	case proposal := <-restateCtx.runClosureCompletions:
+		// Propose completion
+		if err := restateCtx.stateMachine.ProposeRunCompletion(restateCtx, proposal); err != nil {
+			panic(err)
+		}
+
+		// Pump output once. This is needed for the run completion to be effectively written
+		if err := takeOutputAndWriteOut(restateCtx, restateCtx.stateMachine, restateCtx.stream); err != nil {
+			panic(err)
 		}
+	case <-restateCtx.Done():
+		panic(restateCtx.Err())
 	}
 }
+
+// executeRun runs the Run closure of handle in a separate goroutine, sending its result to
+// runClosureCompletions.
+func (restateCtx *ctx) executeRun(handle uint32) {
+	closure, ok := restateCtx.runClosures[handle]
+	if !ok {
+		panic(fmt.Sprintf("Need to run a Run closure with coreHandle %d, but it doesn't exist. This is an SDK bug.", handle))
+	}
+
+	// Delete this closure from the running list
+	delete(restateCtx.runClosures, handle)
+
+	// Run closure in a separate goroutine, proposing the result to runClosureCompletions
+	go func(runClosureCompletions chan *pbinternal.VmProposeRunCompletionParameters, closure func() *pbinternal.VmProposeRunCompletionParameters) {
+		runClosureCompletions <- closure()
+	}(restateCtx.runClosureCompletions, closure)
+}
@@ -36,6 +36,15 @@ type client struct {
 	method         string
 }
 
+// inputCodec returns the codec of the input of the calls, defaulting to the codec registered
+// for the type of input.
+func (c *client) inputCodec(input any) encoding.Codec {
+	if c.options.InputCodec != nil {
+		return c.options.InputCodec
+	}
+	return encoding.DefaultCodecFor(input)
+}
+
 // RequestFuture makes a call and returns a coreHandle on the response
 func (c *client) RequestFuture(input any, opts ...options.RequestOption) ResponseFuture {
 	o := options.RequestOptions{Scope: c.options.Scope}
@@ -43,7 +52,8 @@ func (c *client) RequestFuture(input any, opts ...options.RequestOption) Respons
 		opt.BeforeRequest(&o)
 	}
 
-	inputBytes, err := encoding.Marshal(c.options.InputCodec, input)
+	inputCodec := c.inputCodec(input)
+	inputBytes, err := encoding.Marshal(inputCodec, input)
 	if err != nil {
 		panic(fmt.Errorf("failed to marshal RequestFuture input: %w", err))
 	}
@@ -75,7 +85,7 @@ func (c *client) RequestFuture(input any, opts ...options.RequestOption) Respons
 	}
 	inputParams.SetInput(inputBytes)
 	inputParams.SetUnstableSerialization(
-		encoding.IsNonDeterministicSerialization(c.options.InputCodec),
+		encoding.IsNonDeterministicSerialization(inputCodec),
 	)
 
 	invocationIdHandle, resultHandle, err := c.restateContext.stateMachine.SysCall(c.restateContext, &inputParams)
@@ -144,7 +154,8 @@ func (c *client) Send(input any, opts ...options.SendOption) Invocation {
 		opt.BeforeSend(&o)
 	}
 
-	inputBytes, err := encoding.Marshal(c.options.InputCodec, input)
+	inputCodec := c.inputCodec(input)
+	inputBytes, err := encoding.Marshal(inputCodec, input)
 	if err != nil {
 		panic(fmt.Errorf("failed to marshal RequestFuture input: %w", err))
 	}
@@ -179,7 +190,7 @@ func (c *client) Send(input any, opts ...options.SendOption) Invocation {
 		inputParams.SetExecutionTimeSinceUnixEpochMillis(uint64(time.Now().Add(o.Delay).UnixMilli()))
 	}
 	inputParams.SetUnstableSerialization(
-		encoding.IsNonDeterministicSerialization(c.options.InputCodec),
+		encoding.IsNonDeterministicSerialization(inputCodec),
 	)
 
 	invocationIdHandle, err := c.restateContext.stateMachine.SysSend(c.restateContext, &inputParams)
@@ -198,9 +209,7 @@ func (restateCtx *ctx) Service(service, method string, opts ...options.ClientOpt
 	for _, opt := range opts {
 		opt.BeforeClient(&o)
 	}
-	if o.InputCodec == nil {
-		o.InputCodec = encoding.JSONCodec
-	}
+	// the input codec defaults to the one registered for the type of the input, see inputCodec
 	if o.OutputCodec == nil {
 		o.OutputCodec = encoding.JSONCodec
 	}
@@ -218,9 +227,7 @@ func (restateCtx *ctx) Object(service, key, method string, opts ...options.Clien
 	for _, opt := range opts {
 		opt.BeforeClient(&o)
 	}
-	if o.InputCodec == nil {
-		o.InputCodec = encoding.JSONCodec
-	}
+	// the input codec defaults to the one registered for the type of the input, see inputCodec
 	if o.OutputCodec == nil {
 		o.OutputCodec = encoding.JSONCodec
 	}
@@ -239,9 +246,7 @@ func (restateCtx *ctx) Workflow(service, workflowID, method string, opts ...opti
 	for _, opt := range opts {
 		opt.BeforeClient(&o)
 	}
-	if o.InputCodec == nil {
-		o.InputCodec = encoding.JSONCodec
-	}
+	// the input codec defaults to the one registered for the type of the input, see inputCodec
 	if o.OutputCodec == nil {
 		o.OutputCodec = encoding.JSONCodec
 	}
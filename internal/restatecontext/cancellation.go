@@ -0,0 +1,25 @@
+package restatecontext
+
+// OnCancel registers fn to run when the handler returns an error after the cancel signal was
+// received. Hooks run in a shielded scope, in the reverse order of their registration.
+func (restateCtx *ctx) OnCancel(fn func()) {
+	restateCtx.cancelHooks = append(restateCtx.cancelHooks, fn)
+}
+
+// Shield runs fn without letting the cancel signal interrupt its operations. A cancel signal
+// received meanwhile interrupts the first operation awaited after fn returns.
+func (restateCtx *ctx) Shield(fn func()) {
+	restateCtx.shielded++
+	defer func() { restateCtx.shielded-- }()
+	fn()
+}
+
+func (restateCtx *ctx) runCancelHooks() {
+	hooks := restateCtx.cancelHooks
+	restateCtx.cancelHooks = nil
+	restateCtx.Shield(func() {
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hooks[i]()
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package restatecontext
+
+import (
+	"testing"
+	"time"
+
+	"github.com/restatedev/sdk-go/internal/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShield(t *testing.T) {
+	var shieldedErr, afterErr error
+	invocation := startTestInvocation(t, func(ctx Context) error {
+		ctx.Shield(func() {
+			shieldedErr = ctx.Sleep(time.Second)
+		})
+		afterErr = ctx.Sleep(time.Second)
+		return afterErr
+	})
+
+	shielded := invocation.expectSleep()
+	invocation.send(cancelSignal())
+	invocation.send(sleepCompletion(shielded))
+	invocation.expectSleep()
+	invocation.expectFailure(uint64(errors.CancelledCode))
+
+	<-invocation.done
+	require.NoError(t, shieldedErr)
+	require.True(t, errors.IsCancellation(afterErr))
+}
+
+func TestNestedShields(t *testing.T) {
+	var innerErr, outerErr, afterErr error
+	var output string
+	invocation := startTestInvocation(t, func(ctx Context) error {
+		ctx.Shield(func() {
+			ctx.Shield(func() {
+				innerErr = ctx.Sleep(time.Second)
+			})
+			outerErr = ctx.Run(func(RunContext) (any, error) {
+				return "cleaned up", nil
+			}, &output)
+		})
+		afterErr = ctx.Sleep(time.Second)
+		return afterErr
+	})
+
+	inner := invocation.expectSleep()
+	invocation.send(cancelSignal())
+	invocation.send(sleepCompletion(inner))
+	run := invocation.expectRun()
+	invocation.send(runCompletion(run, []byte(`"cleaned up"`)))
+	invocation.expectSleep()
+	invocation.expectFailure(uint64(errors.CancelledCode))
+
+	<-invocation.done
+	require.NoError(t, innerErr)
+	require.NoError(t, outerErr)
+	require.Equal(t, "cleaned up", output)
+	require.True(t, errors.IsCancellation(afterErr))
+}
+
+func TestOnCancel(t *testing.T) {
+	var hooks []string
+	var hookErr error
+	invocation := startTestInvocation(t, func(ctx Context) error {
+		ctx.OnCancel(func() {
+			hooks = append(hooks, "first")
+			hookErr = ctx.Sleep(time.Second)
+		})
+		ctx.OnCancel(func() {
+			hooks = append(hooks, "second")
+		})
+		return ctx.Sleep(time.Second)
+	})
+
+	invocation.expectSleep()
+	invocation.send(cancelSignal())
+	hook := invocation.expectSleep()
+	invocation.send(sleepCompletion(hook))
+	invocation.expectFailure(uint64(errors.CancelledCode))
+
+	<-invocation.done
+	require.Equal(t, []string{"second", "first"}, hooks)
+	require.NoError(t, hookErr)
+}
+
+func TestOnCancelWithClassifiedError(t *testing.T) {
+	var hooks int
+	classifier := func(err error) error {
+		return errors.NewTerminalError("order aborted", errors.WithCode(500))
+	}
+	invocation := startClassifiedTestInvocation(t, classifier, func(ctx Context) error {
+		ctx.OnCancel(func() {
+			hooks++
+		})
+		return ctx.Sleep(time.Second)
+	})
+
+	invocation.expectSleep()
+	invocation.send(cancelSignal())
+	invocation.expectFailure(500)
+
+	<-invocation.done
+	require.Equal(t, 1, hooks)
+}
+
+func TestOnCancelNotCancelled(t *testing.T) {
+	var hooks int
+	invocation := startTestInvocation(t, func(ctx Context) error {
+		ctx.OnCancel(func() {
+			hooks++
+		})
+		return errors.NewTerminalError("cancelled", errors.WithCode(errors.CancelledCode))
+	})
+
+	invocation.expectFailure(uint64(errors.CancelledCode))
+
+	<-invocation.done
+	require.Zero(t, hooks)
+}
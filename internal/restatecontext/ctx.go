@@ -23,6 +23,10 @@ type Request struct {
 	// The unique id that identifies the current function invocation. This id is guaranteed to be
 	// unique across invocations, but constant across reties and suspensions.
 	ID string
+	// Service is the name of the invoked service, virtual object or workflow.
+	Service string
+	// Handler is the name of the invoked handler.
+	Handler string
 	// Scope is the invocation scope supplied by the runtime.
 	Scope string
 	// LimitKey is the invocation concurrency limit key supplied by the runtime.
@@ -64,6 +68,8 @@ type Context interface {
 	ResolveSignal(invocationID string, name string, value any, options ...options.ResolveSignalOption)
 	RejectSignal(invocationID string, name string, reason error)
 	WaitIter(futs ...Future) WaitIterator
+	OnCancel(fn func())
+	Shield(fn func())
 	Run(fn func(ctx RunContext) (any, error), output any, options ...options.RunOption) errors.TerminalError
 	RunAsync(fn func(ctx RunContext) (any, error), options ...options.RunOption) RunAsyncFuture
 
@@ -110,17 +116,32 @@ type ctx struct {
 	// Run implementation
 	runClosures           map[uint32]func() *pbinternal.VmProposeRunCompletionParameters
 	runClosureCompletions chan *pbinternal.VmProposeRunCompletionParameters
+
+	// Converts the errors of the handler and of Run closures into terminal or retryable errors, if set
+	errorClassifier func(error) error
+
+	// Cancellation
+	cancelHooks []func()
+	// cancelReceived is set once the cancel signal was received
+	cancelReceived bool
+	// shielded counts the nested shielded scopes, which defer the cancel signal to cancelPending
+	shielded      int
+	cancelPending bool
+	// inputClosed is set once the input stream was closed
+	inputClosed bool
 }
 
 var _ Context = (*ctx)(nil)
 
-func newContext(inner context.Context, machine *statemachine.StateMachine, invocationInput *pbinternal.VmSysInputReturn_Input, stream io.ReadWriter, attemptHeaders map[string][]string, dropReplayLogs bool, logHandler slog.Handler) *ctx {
+func newContext(inner context.Context, machine *statemachine.StateMachine, invocationInput *pbinternal.VmSysInputReturn_Input, stream io.ReadWriter, service, handler string, attemptHeaders map[string][]string, dropReplayLogs bool, logHandler slog.Handler) *ctx {
 	headers := make(map[string]string)
 	for _, h := range invocationInput.GetHeaders() {
 		headers[h.GetKey()] = h.GetValue()
 	}
 	request := Request{
 		ID:             invocationInput.GetInvocationId(),
+		Service:        service,
+		Handler:        handler,
 		Scope:          invocationInput.GetScope(),
 		LimitKey:       invocationInput.GetLimitKey(),
 		IdempotencyKey: invocationInput.GetIdempotencyKey(),
@@ -0,0 +1,88 @@
+package restatecontext
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/restatedev/sdk-go/internal/errors"
+)
+
+var (
+	errNotFound    = stderrors.New("not found")
+	errUnavailable = stderrors.New("unavailable")
+)
+
+func testClassifier(err error) errors.Classification {
+	switch {
+	case stderrors.Is(err, errNotFound):
+		return errors.Classification{Kind: errors.Terminal, Code: 404}
+	case stderrors.Is(err, errUnavailable):
+		return errors.Classification{Kind: errors.Retryable, Code: 503}
+	default:
+		return errors.Classification{}
+	}
+}
+
+func TestErrorClassifierHandler(t *testing.T) {
+	classifier := errors.Classifier(testClassifier)
+
+	t.Run("terminal", func(t *testing.T) {
+		invocation := startClassifiedTestInvocation(t, classifier, func(ctx Context) error {
+			return errNotFound
+		})
+		invocation.expectFailure(404)
+	})
+
+	t.Run("retryable", func(t *testing.T) {
+		invocation := startClassifiedTestInvocation(t, classifier, func(ctx Context) error {
+			return errUnavailable
+		})
+		// The state machine reports the failures of handlers with the default code
+		invocation.expectError(500)
+	})
+
+	t.Run("unclassified", func(t *testing.T) {
+		invocation := startClassifiedTestInvocation(t, classifier, func(ctx Context) error {
+			return stderrors.New("boom")
+		})
+		invocation.expectError(500)
+	})
+
+	t.Run("terminal errors are kept", func(t *testing.T) {
+		invocation := startClassifiedTestInvocation(t, classifier, func(ctx Context) error {
+			return errors.NewTerminalError("conflict", errors.WithCode(409))
+		})
+		invocation.expectFailure(409)
+	})
+}
+
+func TestErrorClassifierRun(t *testing.T) {
+	classifier := errors.Classifier(testClassifier)
+	runFailing := func(runErr error) testHandler {
+		return func(ctx Context) error {
+			var output string
+			return ctx.Run(func(RunContext) (any, error) {
+				return nil, runErr
+			}, &output)
+		}
+	}
+
+	t.Run("terminal", func(t *testing.T) {
+		invocation := startClassifiedTestInvocation(t, classifier, runFailing(errNotFound))
+		run := invocation.expectRunFailure(404)
+		invocation.send(runFailure(run, 404))
+		invocation.expectFailure(404)
+	})
+
+	t.Run("retryable", func(t *testing.T) {
+		invocation := startClassifiedTestInvocation(t, classifier, runFailing(errUnavailable))
+		invocation.expect(runCommandType)
+		invocation.expectError(503)
+	})
+
+	t.Run("no classifier", func(t *testing.T) {
+		invocation := startTestInvocation(t, runFailing(errNotFound))
+		invocation.expect(runCommandType)
+		invocation.expectError(500)
+	})
+}
@@ -14,7 +14,7 @@ import (
 	"github.com/restatedev/sdk-go/internal/statemachine"
 )
 
-func ExecuteInvocation(ctx context.Context, logger *slog.Logger, stateMachine *statemachine.StateMachine, stream io.ReadWriter, handler Handler, dropReplayLogs bool, logHandler slog.Handler, attemptHeaders map[string][]string) error {
+func ExecuteInvocation(ctx context.Context, logger *slog.Logger, stateMachine *statemachine.StateMachine, stream io.ReadWriter, service, handlerName string, handler Handler, dropReplayLogs bool, logHandler slog.Handler, attemptHeaders map[string][]string, errorClassifier func(error) error) error {
 	// Let's read the input entry
 	invocationInput, err := stateMachine.SysInput(ctx)
 	if err != nil {
@@ -27,7 +27,8 @@ func ExecuteInvocation(ctx context.Context, logger *slog.Logger, stateMachine *s
 	}
 
 	// Instantiate the restate context
-	restateCtx := newContext(ctx, stateMachine, invocationInput, stream, attemptHeaders, dropReplayLogs, logHandler)
+	restateCtx := newContext(ctx, stateMachine, invocationInput, stream, service, handlerName, attemptHeaders, dropReplayLogs, logHandler)
+	restateCtx.errorClassifier = errorClassifier
 
 	// Invoke the handler
 	invoke(restateCtx, handler, logger)
@@ -53,7 +54,7 @@ func invoke(restateCtx *ctx, handler Handler, logger *slog.Logger) {
 		case statemachine.SuspensionError:
 			restateCtx.internalLogger.LogAttrs(restateCtx, slog.LevelInfo, "Suspending invocation")
 		default:
-			restateCtx.internalLogger.LogAttrs(restateCtx, slog.LevelError, "Invocation panicked, returning error to Restate", slog.Any("err", typ))
+			restateCtx.internalLogger.LogAttrs(restateCtx, slog.LevelError, "Invocation panicked, returning error to Restate", log.Payload("err", typ))
 
 			if err := restateCtx.stateMachine.NotifyError(restateCtx, fmt.Sprint(typ), string(debug.Stack())); err != nil {
 				restateCtx.internalLogger.WarnContext(restateCtx, "Error when notifying error to state restateContext", log.Error(err))
@@ -72,8 +73,14 @@ func invoke(restateCtx *ctx, handler Handler, logger *slog.Logger) {
 	var err error
 	bytes, err = handler.Call(restateCtx, restateCtx.request.Body)
 
+	if err != nil && restateCtx.cancelReceived && len(restateCtx.cancelHooks) > 0 {
+		restateCtx.internalLogger.InfoContext(restateCtx, "Invocation cancelled, running cancellation hooks")
+		restateCtx.runCancelHooks()
+	}
+	err = restateCtx.classifyError(err)
+
 	if err != nil && errors.IsTerminalError(err) {
-		restateCtx.internalLogger.LogAttrs(restateCtx, slog.LevelWarn, "Invocation returned a terminal failure", log.Error(err))
+		restateCtx.internalLogger.LogAttrs(restateCtx, slog.LevelWarn, "Invocation returned a terminal failure", log.PayloadError(err))
 
 		outputParameters := pbinternal.VmSysWriteOutputParameters{}
 		outputParameters.SetFailure(newFailureFromError(err))
@@ -85,7 +92,7 @@ func invoke(restateCtx *ctx, handler Handler, logger *slog.Logger) {
 			panic(err)
 		}
 	} else if err != nil {
-		restateCtx.internalLogger.LogAttrs(restateCtx, slog.LevelWarn, "Invocation returned a non-terminal failure", log.Error(err))
+		restateCtx.internalLogger.LogAttrs(restateCtx, slog.LevelWarn, "Invocation returned a non-terminal failure", log.PayloadError(err))
 
 		// This is handled by the panic catcher above
 		panic(err)
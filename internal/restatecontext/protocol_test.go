@@ -0,0 +1,324 @@
+package restatecontext
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal"
+	pbinternal "github.com/restatedev/sdk-go/internal/generated"
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/restatedev/sdk-go/internal/statemachine"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Types of the service protocol messages exchanged in the tests.
+const (
+	startMessageType                uint16 = 0x0000
+	errorMessageType                uint16 = 0x0002
+	endMessageType                  uint16 = 0x0003
+	inputCommandType                uint16 = 0x0400
+	outputCommandType               uint16 = 0x0401
+	proposeRunCompletionType        uint16 = 0x0005
+	sleepCommandType                uint16 = 0x040C
+	runCommandType                  uint16 = 0x0411
+	sleepCompletionNotificationType uint16 = 0x800C
+	runCompletionNotificationType   uint16 = 0x8011
+	signalNotificationType          uint16 = 0xFBFF
+	cancelSignalID                  uint64 = 1
+	outputCommandFailureField              = 15
+	proposalFailureField                   = 15
+	failureCodeField                       = 1
+	errorCodeField                         = 1
+	notificationCompletionIDField          = 1
+	notificationSignalIDField              = 2
+	notificationVoidField                  = 4
+	notificationValueField                 = 5
+	notificationFailureField               = 6
+	commandResultCompletionIDField         = 11
+)
+
+type protocolMessage struct {
+	typ  uint16
+	body []byte
+}
+
+func encodeMessage(typ uint16, body []byte) []byte {
+	frame := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint16(frame[0:2], typ)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(body)))
+	return append(frame, body...)
+}
+
+func startMessage() []byte {
+	var body []byte
+	body = protowire.AppendTag(body, 1, protowire.BytesType)
+	body = protowire.AppendBytes(body, []byte("invocation-id"))
+	body = protowire.AppendTag(body, 2, protowire.BytesType)
+	body = protowire.AppendString(body, "inv_test")
+	body = protowire.AppendTag(body, 3, protowire.VarintType)
+	body = protowire.AppendVarint(body, 1)
+	return encodeMessage(startMessageType, body)
+}
+
+func inputCommand(input []byte) []byte {
+	var value []byte
+	value = protowire.AppendTag(value, 1, protowire.BytesType)
+	value = protowire.AppendBytes(value, input)
+	var body []byte
+	body = protowire.AppendTag(body, 14, protowire.BytesType)
+	body = protowire.AppendBytes(body, value)
+	return encodeMessage(inputCommandType, body)
+}
+
+func sleepCompletion(completionID uint64) []byte {
+	var body []byte
+	body = protowire.AppendTag(body, notificationCompletionIDField, protowire.VarintType)
+	body = protowire.AppendVarint(body, completionID)
+	body = protowire.AppendTag(body, notificationVoidField, protowire.BytesType)
+	body = protowire.AppendBytes(body, nil)
+	return encodeMessage(sleepCompletionNotificationType, body)
+}
+
+func runCompletion(completionID uint64, value []byte) []byte {
+	var content []byte
+	content = protowire.AppendTag(content, 1, protowire.BytesType)
+	content = protowire.AppendBytes(content, value)
+	var body []byte
+	body = protowire.AppendTag(body, notificationCompletionIDField, protowire.VarintType)
+	body = protowire.AppendVarint(body, completionID)
+	body = protowire.AppendTag(body, notificationValueField, protowire.BytesType)
+	body = protowire.AppendBytes(body, content)
+	return encodeMessage(runCompletionNotificationType, body)
+}
+
+func runFailure(completionID uint64, code uint64) []byte {
+	var failure []byte
+	failure = protowire.AppendTag(failure, failureCodeField, protowire.VarintType)
+	failure = protowire.AppendVarint(failure, code)
+	var body []byte
+	body = protowire.AppendTag(body, notificationCompletionIDField, protowire.VarintType)
+	body = protowire.AppendVarint(body, completionID)
+	body = protowire.AppendTag(body, notificationFailureField, protowire.BytesType)
+	body = protowire.AppendBytes(body, failure)
+	return encodeMessage(runCompletionNotificationType, body)
+}
+
+func cancelSignal() []byte {
+	var body []byte
+	body = protowire.AppendTag(body, notificationSignalIDField, protowire.VarintType)
+	body = protowire.AppendVarint(body, cancelSignalID)
+	body = protowire.AppendTag(body, notificationVoidField, protowire.BytesType)
+	body = protowire.AppendBytes(body, nil)
+	return encodeMessage(signalNotificationType, body)
+}
+
+// varintField returns the value of the varint field number of the protobuf message body.
+func varintField(body []byte, number protowire.Number) (uint64, bool) {
+	for len(body) > 0 {
+		num, typ, n := protowire.ConsumeTag(body)
+		if n < 0 {
+			return 0, false
+		}
+		body = body[n:]
+		if num == number && typ == protowire.VarintType {
+			v, _ := protowire.ConsumeVarint(body)
+			return v, true
+		}
+		n = protowire.ConsumeFieldValue(num, typ, body)
+		if n < 0 {
+			return 0, false
+		}
+		body = body[n:]
+	}
+	return 0, false
+}
+
+// messageField returns the value of the message field number of the protobuf message body.
+func messageField(body []byte, number protowire.Number) ([]byte, bool) {
+	for len(body) > 0 {
+		num, typ, n := protowire.ConsumeTag(body)
+		if n < 0 {
+			return nil, false
+		}
+		body = body[n:]
+		if num == number && typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(body)
+			return v, true
+		}
+		n = protowire.ConsumeFieldValue(num, typ, body)
+		if n < 0 {
+			return nil, false
+		}
+		body = body[n:]
+	}
+	return nil, false
+}
+
+// testInvocation runs a handler against the state machine, exchanging service protocol messages
+// with the test like the runtime would over a bidirectional stream.
+type testInvocation struct {
+	t        *testing.T
+	input    *io.PipeWriter
+	output   chan protocolMessage
+	done     chan struct{}
+	mu       sync.Mutex
+	buffered []byte
+}
+
+func (i *testInvocation) Write(p []byte) (int, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.buffered = append(i.buffered, p...)
+	for len(i.buffered) >= 8 {
+		length := int(binary.BigEndian.Uint32(i.buffered[4:8]))
+		if len(i.buffered) < 8+length {
+			break
+		}
+		i.output <- protocolMessage{
+			typ:  binary.BigEndian.Uint16(i.buffered[0:2]),
+			body: append([]byte(nil), i.buffered[8:8+length]...),
+		}
+		i.buffered = i.buffered[8+length:]
+	}
+	return len(p), nil
+}
+
+type testHandler func(ctx Context) error
+
+func (h testHandler) GetOptions() *options.HandlerOptions {
+	return &options.HandlerOptions{InputCodec: encoding.BinaryCodec, OutputCodec: encoding.BinaryCodec}
+}
+func (h testHandler) InputPayload() *encoding.InputPayload      { return nil }
+func (h testHandler) OutputPayload() *encoding.OutputPayload    { return nil }
+func (h testHandler) HandlerType() *internal.ServiceHandlerType { return nil }
+func (h testHandler) Call(ctx Context, _ []byte) ([]byte, error) {
+	return nil, h(ctx)
+}
+
+func startTestInvocation(t *testing.T, handler testHandler) *testInvocation {
+	return startClassifiedTestInvocation(t, nil, handler)
+}
+
+// startClassifiedTestInvocation is like startTestInvocation, classifying the errors of the
+// handler with errorClassifier.
+func startClassifiedTestInvocation(t *testing.T, errorClassifier func(error) error, handler testHandler) *testInvocation {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	core, err := statemachine.NewCore(ctx)
+	require.NoError(t, err)
+	contentType := pbinternal.Header{}
+	contentType.SetKey("content-type")
+	contentType.SetValue("application/vnd.restate.invocation.v5")
+	stateMachine, err := core.NewStateMachine(ctx, []*pbinternal.Header{&contentType})
+	require.NoError(t, err)
+
+	require.NoError(t, stateMachine.NotifyInput(ctx, append(startMessage(), inputCommand(nil)...)))
+	ready, err := stateMachine.IsReadyToExecute(ctx)
+	require.NoError(t, err)
+	require.True(t, ready)
+
+	reader, writer := io.Pipe()
+	invocation := &testInvocation{
+		t:      t,
+		input:  writer,
+		output: make(chan protocolMessage, 100),
+		done:   make(chan struct{}),
+	}
+	stream := struct {
+		io.Reader
+		io.Writer
+	}{reader, invocation}
+	logger := slog.New(slog.DiscardHandler)
+
+	go func() {
+		defer close(invocation.done)
+		defer writer.Close()
+		err := ExecuteInvocation(ctx, logger, stateMachine, stream, "Service", "handler", handler, false, slog.DiscardHandler, nil, errorClassifier)
+		if err != nil {
+			panic(fmt.Sprintf("execute invocation: %v", err))
+		}
+	}()
+	return invocation
+}
+
+// send sends the protocol messages to the invocation.
+func (i *testInvocation) send(messages ...[]byte) {
+	for _, message := range messages {
+		_, err := i.input.Write(message)
+		require.NoError(i.t, err)
+	}
+}
+
+// expect waits for the next message written by the invocation, which must be of type typ.
+func (i *testInvocation) expect(typ uint16) []byte {
+	select {
+	case message := <-i.output:
+		require.Equalf(i.t, typ, message.typ, "expected message of type %#x, got %#x", typ, message.typ)
+		return message.body
+	case <-time.After(5 * time.Second):
+		require.FailNowf(i.t, "timeout", "waiting for message of type %#x", typ)
+		return nil
+	}
+}
+
+// expectSleep waits for a sleep command, and returns its completion id.
+func (i *testInvocation) expectSleep() uint64 {
+	id, ok := varintField(i.expect(sleepCommandType), commandResultCompletionIDField)
+	require.True(i.t, ok)
+	return id
+}
+
+// expectRun waits for a run command and the proposal of its result, and returns its completion
+// id.
+func (i *testInvocation) expectRun() uint64 {
+	id, ok := varintField(i.expect(runCommandType), commandResultCompletionIDField)
+	require.True(i.t, ok)
+	i.expect(proposeRunCompletionType)
+	return id
+}
+
+// expectRunFailure waits for a run command and the proposal of its failure, which must have
+// the given code, and returns its completion id.
+func (i *testInvocation) expectRunFailure(code uint64) uint64 {
+	id, ok := varintField(i.expect(runCommandType), commandResultCompletionIDField)
+	require.True(i.t, ok)
+	failure, ok := messageField(i.expect(proposeRunCompletionType), proposalFailureField)
+	require.True(i.t, ok, "proposal is not a failure")
+	actual, _ := varintField(failure, failureCodeField)
+	require.Equal(i.t, code, actual)
+	return id
+}
+
+// expectError waits for the error message of an invocation to be retried, which must have the
+// given code.
+func (i *testInvocation) expectError(code uint64) {
+	actual, _ := varintField(i.expect(errorMessageType), errorCodeField)
+	require.Equal(i.t, code, actual)
+}
+
+// expectFailure waits for the output command of the invocation, which must be a failure with
+// the given code, and the end message.
+func (i *testInvocation) expectFailure(code uint64) {
+	failure, ok := messageField(i.expect(outputCommandType), outputCommandFailureField)
+	require.True(i.t, ok, "output is not a failure")
+	actual, _ := varintField(failure, failureCodeField)
+	require.Equal(i.t, code, actual)
+	i.expect(endMessageType)
+}
+
+// expectSuccess waits for the successful output command of the invocation and the end message.
+func (i *testInvocation) expectSuccess() {
+	body := i.expect(outputCommandType)
+	_, failed := messageField(body, outputCommandFailureField)
+	require.False(i.t, failed, "output is a failure")
+	i.expect(endMessageType)
+}
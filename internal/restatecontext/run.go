@@ -2,8 +2,10 @@ package restatecontext
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"runtime/debug"
 	"time"
 
@@ -46,35 +48,29 @@ func (restateCtx *ctx) runAsync(goCtx context.Context, fn func(ctx RunContext) (
 
 			// Run the user closure
 			output, err := runWrapPanic(fn)(runContext{Context: goCtx, log: restateCtx.userLogger, request: &restateCtx.request})
+			err = restateCtx.classifyError(err)
 
 			// Let's prepare the proposal of the run completion
 			proposal := pbinternal.VmProposeRunCompletionParameters{}
 			proposal.SetHandle(handle)
 			proposal.SetAttemptDurationMillis(uint64(time.Now().Sub(now).Milliseconds()))
 
-			// Set retry policy if any of the retry policy config options are set
-			if o.MaxRetryAttempts != nil || o.MaxRetryInterval != nil || o.MaxRetryDuration != nil || o.RetryIntervalFactor != nil || o.InitialRetryInterval != nil {
-				retryPolicy := pbinternal.VmProposeRunCompletionParameters_RetryPolicy{}
-				retryPolicy.SetInitialInternalMillis(50)
-				retryPolicy.SetFactor(2)
-				retryPolicy.SetMaxIntervalMillis(2000)
+			if err != nil && o.RetryIf != nil && !errors.IsTerminalError(err) && !o.RetryIf(err) {
+				// The failure is not worth retrying
+				err = retryableToTerminal(err)
+			}
 
-				if o.MaxRetryDuration != nil {
-					retryPolicy.SetMaxDurationMillis(uint64((*o.MaxRetryDuration).Milliseconds()))
-				}
-				if o.MaxRetryInterval != nil {
-					retryPolicy.SetMaxIntervalMillis(uint64((*o.MaxRetryInterval).Milliseconds()))
-				}
-				if o.RetryIntervalFactor != nil {
-					retryPolicy.SetFactor(*o.RetryIntervalFactor)
-				}
-				if o.MaxRetryAttempts != nil {
-					retryPolicy.SetMaxAttempts(uint32(*o.MaxRetryAttempts))
-				}
-				if o.InitialRetryInterval != nil {
-					retryPolicy.SetInitialInternalMillis(uint64((*o.InitialRetryInterval).Milliseconds()))
-				}
-				proposal.SetRetryPolicy(&retryPolicy)
+			if err != nil && o.CircuitBreaker != "" && !errors.IsTerminalError(err) {
+				// The failure is recorded in the circuit breaker by the caller, which retries
+				err = retryableToTerminal(err, errors.WithMetadata(map[string]string{errors.CircuitBreakerFailureMetadataKey: o.CircuitBreaker}))
+			}
+
+			var retryAfter time.Duration
+			if re := errors.AsRetryableError(err); re != nil && !errors.IsTerminalError(err) {
+				retryAfter = re.RetryAfter()
+			}
+			if retryPolicy := runRetryPolicy(&o, retryAfter, rand.Float64); retryPolicy != nil {
+				proposal.SetRetryPolicy(retryPolicy)
 			}
 
 			if errors.IsTerminalError(err) {
@@ -112,6 +108,66 @@ func (restateCtx *ctx) runAsync(goCtx context.Context, fn func(ctx RunContext) (
 	}
 }
 
+// retryableToTerminal converts a non-terminal failure into a terminal error, keeping the code
+// and message of a RetryableError.
+func retryableToTerminal(err error, opts ...errors.TerminalErrorOption) errors.TerminalError {
+	code, message := errors.DefaultCode, err.Error()
+	if re := errors.AsRetryableError(err); re != nil {
+		code, message = re.Code(), re.Message()
+	}
+	return errors.NewTerminalError(message, append([]errors.TerminalErrorOption{errors.WithCode(code)}, opts...)...)
+}
+
+// runRetryPolicy returns the retry policy of a Run attempt, or nil to use the default one
+// when no retry option is set. A retryAfter delay, suggested by the failure, overrides the
+// interval before the next attempt. Jitter scales the intervals by a random factor, only
+// lengthening a retryAfter delay.
+func runRetryPolicy(o *options.RunOptions, retryAfter time.Duration, random func() float64) *pbinternal.VmProposeRunCompletionParameters_RetryPolicy {
+	if o.MaxRetryAttempts == nil && o.MaxRetryDuration == nil && o.RetryIntervalFactor == nil && o.InitialRetryInterval == nil && o.MaxRetryInterval == nil &&
+		o.RetryJitter == 0 && retryAfter == 0 {
+		return nil
+	}
+
+	initialInterval, factor, maxInterval := 50*time.Millisecond, float32(2), 2*time.Second
+	if o.InitialRetryInterval != nil {
+		initialInterval = *o.InitialRetryInterval
+	}
+	if o.RetryIntervalFactor != nil {
+		factor = *o.RetryIntervalFactor
+	}
+	if o.MaxRetryInterval != nil {
+		maxInterval = *o.MaxRetryInterval
+	}
+
+	jitter := min(max(o.RetryJitter, 0), 1)
+	if retryAfter > 0 {
+		// Wait exactly retryAfter, whatever the attempt
+		initialInterval, factor, maxInterval = retryAfter, 1, retryAfter
+		if jitter > 0 {
+			scale := 1 + jitter*random()
+			initialInterval = time.Duration(float64(initialInterval) * scale)
+			maxInterval = initialInterval
+		}
+	} else if jitter > 0 {
+		// Scaling both the initial and the max intervals scales the interval of this attempt
+		scale := 1 - jitter + 2*jitter*random()
+		initialInterval = time.Duration(float64(initialInterval) * scale)
+		maxInterval = time.Duration(float64(maxInterval) * scale)
+	}
+
+	retryPolicy := pbinternal.VmProposeRunCompletionParameters_RetryPolicy{}
+	retryPolicy.SetInitialInternalMillis(uint64(initialInterval.Milliseconds()))
+	retryPolicy.SetFactor(factor)
+	retryPolicy.SetMaxIntervalMillis(uint64(maxInterval.Milliseconds()))
+	if o.MaxRetryDuration != nil {
+		retryPolicy.SetMaxDurationMillis(uint64((*o.MaxRetryDuration).Milliseconds()))
+	}
+	if o.MaxRetryAttempts != nil {
+		retryPolicy.SetMaxAttempts(uint32(*o.MaxRetryAttempts))
+	}
+	return &retryPolicy
+}
+
 func runWrapPanic(fn func(ctx RunContext) (any, error)) func(ctx RunContext) (any, error) {
 	return func(ctx RunContext) (res any, err error) {
 		defer func() {
@@ -135,6 +191,22 @@ func runWrapPanic(fn func(ctx RunContext) (any, error)) func(ctx RunContext) (an
 	}
 }
 
+// classifyError applies the error classifier of the invocation to err, if any. Suspensions
+// are never classified.
+func (restateCtx *ctx) classifyError(err error) error {
+	if err == nil || restateCtx.errorClassifier == nil {
+		return err
+	}
+	var suspension *statemachine.SuspensionError
+	if stderrors.As(err, &suspension) {
+		return err
+	}
+	if _, ok := err.(statemachine.SuspensionError); ok {
+		return err
+	}
+	return restateCtx.errorClassifier(err)
+}
+
 type RunAsyncFuture interface {
 	Future
 	Result(output any) errors.TerminalError
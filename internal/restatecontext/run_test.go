@@ -0,0 +1,52 @@
+package restatecontext
+
+import (
+	"testing"
+	"time"
+
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRetryPolicy(t *testing.T) {
+	half := func() float64 { return 0.5 }
+	one := func() float64 { return 1 }
+
+	t.Run("no retry options", func(t *testing.T) {
+		require.Nil(t, runRetryPolicy(&options.RunOptions{}, 0, half))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		attempts := uint(3)
+		policy := runRetryPolicy(&options.RunOptions{MaxRetryAttempts: &attempts}, 0, half)
+		require.Equal(t, uint64(50), policy.GetInitialInternalMillis())
+		require.Equal(t, float32(2), policy.GetFactor())
+		require.Equal(t, uint64(2000), policy.GetMaxIntervalMillis())
+		require.Equal(t, uint32(3), policy.GetMaxAttempts())
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		initial := time.Second
+		policy := runRetryPolicy(&options.RunOptions{InitialRetryInterval: &initial, RetryJitter: 0.2}, 0, one)
+		require.Equal(t, uint64(1200), policy.GetInitialInternalMillis())
+		require.Equal(t, uint64(2400), policy.GetMaxIntervalMillis())
+
+		policy = runRetryPolicy(&options.RunOptions{InitialRetryInterval: &initial, RetryJitter: 0.2}, 0, func() float64 { return 0 })
+		require.Equal(t, uint64(800), policy.GetInitialInternalMillis())
+	})
+
+	t.Run("retry after", func(t *testing.T) {
+		duration := time.Minute
+		policy := runRetryPolicy(&options.RunOptions{MaxRetryDuration: &duration}, 10*time.Second, half)
+		require.Equal(t, uint64(10000), policy.GetInitialInternalMillis())
+		require.Equal(t, float32(1), policy.GetFactor())
+		require.Equal(t, uint64(10000), policy.GetMaxIntervalMillis())
+		require.Equal(t, uint64(60000), policy.GetMaxDurationMillis())
+	})
+
+	t.Run("retry after with jitter", func(t *testing.T) {
+		policy := runRetryPolicy(&options.RunOptions{RetryJitter: 0.5}, 10*time.Second, half)
+		require.Equal(t, uint64(12500), policy.GetInitialInternalMillis())
+		require.Equal(t, uint64(12500), policy.GetMaxIntervalMillis())
+	})
+}
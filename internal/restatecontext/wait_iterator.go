@@ -85,7 +85,7 @@ func (s *waitIterator) removeAt(i int) Future {
 
 func (s *waitIterator) Err() errors.TerminalError {
 	if s.cancelled {
-		return errors.NewTerminalError("cancelled", errors.WithCode(409))
+		return errors.NewCancellationError()
 	}
 	return nil
 }
@@ -0,0 +1,273 @@
+package restate
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/restatedev/sdk-go/internal/options"
+)
+
+// LockOption is an option for [Lock] and [Semaphore.Acquire].
+type LockOption = options.LockOption
+
+// LocksServiceName is the name of the virtual object returned by [NewLockService]. Each key
+// of the object is a lock or semaphore.
+const LocksServiceName = "RestateLocks"
+
+type withLeaseTimeout struct {
+	leaseTimeout time.Duration
+}
+
+var _ options.LockOption = withLeaseTimeout{}
+
+func (w withLeaseTimeout) BeforeLock(opts *options.LockOptions) {
+	opts.LeaseTimeout = w.leaseTimeout
+}
+
+// WithLeaseTimeout releases the acquired lock or permit after the given duration, even if the
+// holder did not release it and is still running.
+func WithLeaseTimeout(leaseTimeout time.Duration) withLeaseTimeout {
+	return withLeaseTimeout{leaseTimeout}
+}
+
+// Semaphore is a durable counting semaphore, allowing up to Limit invocations to hold a permit
+// at the same time. Waiting invocations are granted permits in FIFO order, and a permit is
+// released automatically when its holder invocation completes.
+//
+// Semaphores are implemented by the virtual object returned by [NewLockService], which must
+// be bound to a server of the same Restate deployment.
+type Semaphore struct {
+	Name  string
+	Limit int
+}
+
+// NewSemaphore returns the semaphore with the given name, allowing up to limit holders.
+func NewSemaphore(name string, limit int) Semaphore {
+	return Semaphore{Name: name, Limit: limit}
+}
+
+// Acquire blocks until the current invocation holds a permit of the semaphore. A permit is
+// held until [Semaphore.Release] is called, the current invocation completes, or the lease
+// set with [WithLeaseTimeout] expires.
+//
+// The first acquire of the semaphore sets its limit: acquiring it with a different limit
+// afterwards fails with code 409.
+func (s Semaphore) Acquire(ctx Context, opts ...options.LockOption) TerminalError {
+	o := options.LockOptions{}
+	for _, opt := range opts {
+		opt.BeforeLock(&o)
+	}
+
+	awakeable := Awakeable[Void](ctx)
+	ObjectSend(ctx, LocksServiceName, s.Name, "Acquire").Send(lockAcquireRequest{
+		Holder:       ctx.Request().ID,
+		AwakeableID:  awakeable.Id(),
+		Limit:        s.Limit,
+		LeaseTimeout: o.LeaseTimeout,
+	})
+	_, err := awakeable.Result()
+	return err
+}
+
+// Release releases the permit held by the current invocation, if any.
+func (s Semaphore) Release(ctx Context) {
+	ObjectSend(ctx, LocksServiceName, s.Name, "Release").Send(lockReleaseRequest{Holder: ctx.Request().ID})
+}
+
+// Lock blocks until the current invocation holds the lock with the given name, a semaphore
+// with a single permit. See [Semaphore.Acquire].
+func Lock(ctx Context, name string, opts ...options.LockOption) TerminalError {
+	return NewSemaphore(name, 1).Acquire(ctx, opts...)
+}
+
+// Unlock releases the lock with the given name, if held by the current invocation.
+func Unlock(ctx Context, name string) {
+	NewSemaphore(name, 1).Release(ctx)
+}
+
+// LockStatus describes the holders and waiters of a lock or semaphore, as returned by the
+// Status handler of the object returned by [NewLockService].
+type LockStatus struct {
+	// Limit is the number of permits, set by the first acquire, or zero before it
+	Limit   int          `json:"limit"`
+	Holders []LockHolder `json:"holders"`
+	Waiting []string     `json:"waiting"`
+}
+
+// LockHolder is an invocation holding a permit of a lock or semaphore.
+type LockHolder struct {
+	Invocation string `json:"invocation"`
+	LeaseID    uint64 `json:"leaseId"`
+}
+
+type lockAcquireRequest struct {
+	Holder       string        `json:"holder"`
+	AwakeableID  string        `json:"awakeableId"`
+	Limit        int           `json:"limit"`
+	LeaseTimeout time.Duration `json:"leaseTimeout,omitempty"`
+}
+
+type lockReleaseRequest struct {
+	Holder string `json:"holder"`
+	// LeaseID, when set, only releases the permit if it was granted with this lease
+	LeaseID uint64 `json:"leaseId,omitempty"`
+}
+
+var (
+	lockLimit     = NewStateKey[int]("limit")
+	lockHolders   = NewStateKey[[]LockHolder]("holders")
+	lockQueue     = NewStateKey[[]lockAcquireRequest]("queue")
+	lockNextLease = NewStateKeyWithDefault[uint64]("nextLease", 1)
+)
+
+// NewLockService returns the virtual object implementing [Lock], [Unlock] and [Semaphore],
+// named [LocksServiceName]. Bind it once to a server of the Restate deployment:
+//
+//	server.NewRestate().Bind(restate.NewLockService())
+//
+// Besides the handlers used by the client helpers, the object exposes a shared Status
+// handler returning a [LockStatus].
+func NewLockService(opts ...options.ServiceDefinitionOption) ServiceDefinition {
+	return NewObject(LocksServiceName, append([]options.ServiceDefinitionOption{
+		WithDocumentation("Durable locks and semaphores, keyed by name."),
+		WithStateKeys(lockLimit, lockHolders, lockQueue, lockNextLease),
+	}, opts...)...).
+		Handler("Acquire", NewObjectHandler(lockAcquire)).
+		Handler("Release", NewObjectHandler(lockRelease)).
+		Handler("WatchHolder", NewObjectSharedHandler(lockWatchHolder)).
+		Handler("Status", NewObjectSharedHandler(lockStatus))
+}
+
+func lockAcquire(ctx ObjectContext, req lockAcquireRequest) (Void, error) {
+	if req.Limit <= 0 {
+		RejectAwakeable(ctx, req.AwakeableID, ToTerminalError(fmt.Errorf("semaphore %s: limit must be positive", Key(ctx)), WithErrorCode(http.StatusBadRequest)))
+		return Void{}, nil
+	}
+	limit, err := lockLimit.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if limit != 0 && limit != req.Limit {
+		RejectAwakeable(ctx, req.AwakeableID, ToTerminalError(fmt.Errorf("semaphore %s has limit %d, not %d", Key(ctx), limit, req.Limit), WithErrorCode(http.StatusConflict)))
+		return Void{}, nil
+	}
+	holders, err := lockHolders.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if slices.ContainsFunc(holders, func(h LockHolder) bool { return h.Invocation == req.Holder }) {
+		RejectAwakeable(ctx, req.AwakeableID, ToTerminalError(fmt.Errorf("semaphore %s is already held by invocation %s", Key(ctx), req.Holder), WithErrorCode(http.StatusConflict)))
+		return Void{}, nil
+	}
+
+	if limit == 0 {
+		// The first acquire sets the limit of the semaphore
+		lockLimit.Set(ctx, req.Limit)
+	}
+	queue, err := lockQueue.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	lockQueue.Set(ctx, append(queue, req))
+	return Void{}, lockGrant(ctx)
+}
+
+func lockRelease(ctx ObjectContext, req lockReleaseRequest) (Void, error) {
+	holders, err := lockHolders.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	released := slices.DeleteFunc(holders, func(h LockHolder) bool {
+		return h.Invocation == req.Holder && (req.LeaseID == 0 || h.LeaseID == req.LeaseID)
+	})
+	if len(released) == len(holders) {
+		// Not held, or the lease was already released
+		return Void{}, nil
+	}
+	if len(released) == 0 {
+		lockHolders.Clear(ctx)
+	} else {
+		lockHolders.Set(ctx, released)
+	}
+	return Void{}, lockGrant(ctx)
+}
+
+// lockGrant grants permits to the waiters at the head of the queue, while some are available.
+func lockGrant(ctx ObjectContext) error {
+	limit, err := lockLimit.Get(ctx)
+	if err != nil {
+		return err
+	}
+	holders, err := lockHolders.Get(ctx)
+	if err != nil {
+		return err
+	}
+	queue, err := lockQueue.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if len(queue) == 0 || len(holders) >= limit {
+		return nil
+	}
+	nextLease, err := lockNextLease.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	for len(queue) > 0 && len(holders) < limit {
+		waiter := queue[0]
+		queue = queue[1:]
+		holder := LockHolder{Invocation: waiter.Holder, LeaseID: nextLease}
+		nextLease++
+		holders = append(holders, holder)
+
+		ResolveAwakeable(ctx, waiter.AwakeableID, Void{})
+		// Release the permit once the holder completes, or its lease expires
+		ObjectSend(ctx, LocksServiceName, Key(ctx), "WatchHolder").Send(holder)
+		if waiter.LeaseTimeout > 0 {
+			ObjectSend(ctx, LocksServiceName, Key(ctx), "Release").
+				Send(lockReleaseRequest{Holder: holder.Invocation, LeaseID: holder.LeaseID}, WithDelay(waiter.LeaseTimeout))
+		}
+	}
+
+	lockHolders.Set(ctx, holders)
+	lockNextLease.Set(ctx, nextLease)
+	if len(queue) == 0 {
+		lockQueue.Clear(ctx)
+	} else {
+		lockQueue.Set(ctx, queue)
+	}
+	return nil
+}
+
+// lockWatchHolder runs concurrently with the exclusive handlers, waiting for the holder
+// invocation to complete before releasing its permit.
+func lockWatchHolder(ctx ObjectSharedContext, holder LockHolder) (Void, error) {
+	// Whatever the outcome, the holder is done with the permit
+	_, _ = AttachInvocation[Void](ctx, holder.Invocation).Response()
+	ObjectSend(ctx, LocksServiceName, Key(ctx), "Release").
+		Send(lockReleaseRequest{Holder: holder.Invocation, LeaseID: holder.LeaseID})
+	return Void{}, nil
+}
+
+func lockStatus(ctx ObjectSharedContext, _ Void) (LockStatus, error) {
+	limit, err := lockLimit.Get(ctx)
+	if err != nil {
+		return LockStatus{}, err
+	}
+	holders, err := lockHolders.Get(ctx)
+	if err != nil {
+		return LockStatus{}, err
+	}
+	queue, err := lockQueue.Get(ctx)
+	if err != nil {
+		return LockStatus{}, err
+	}
+	waiting := make([]string, 0, len(queue))
+	for _, waiter := range queue {
+		waiting = append(waiting, waiter.Holder)
+	}
+	return LockStatus{Limit: limit, Holders: holders, Waiting: waiting}, nil
+}
@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RedactedPlaceholder replaces the redacted values in logs.
+const RedactedPlaceholder = "[REDACTED]"
+
+// Redactable is implemented by the values that must not be logged as is, such as credentials or
+// errors whose message may contain secrets. The SDK logs, and the attributes of the logs of
+// Context.Log(), render such values, and errors wrapping them, as the result of Redacted.
+type Redactable interface {
+	// Redacted returns the representation of the value which is safe to log.
+	Redacted() any
+}
+
+var redactableType = reflect.TypeFor[Redactable]()
+
+// Redact returns the representation of v which is safe to log:
+//   - the result of Redacted if v is [Redactable], or an error wrapping one
+//   - for structs with fields tagged with `restate:"redact"`, or holding values which need to be
+//     redacted, a map of their exported fields (named after their json tag, if any) where the
+//     tagged fields are replaced by [RedactedPlaceholder]; pointers, slices, arrays and maps of
+//     such structs are redacted element by element
+//   - v itself otherwise
+//
+// Fields of interface type are not inspected.
+func Redact(v any) any {
+	if v == nil {
+		return nil
+	}
+	if r, ok := v.(Redactable); ok {
+		return r.Redacted()
+	}
+	if err, ok := v.(error); ok {
+		var r Redactable
+		if errors.As(err, &r) {
+			return r.Redacted()
+		}
+		return v
+	}
+	value := reflect.ValueOf(v)
+	if !needsRedaction(value.Type()) {
+		return v
+	}
+	return redactValue(value)
+}
+
+// redactionNeeds caches whether the values of a type need to be redacted.
+var redactionNeeds sync.Map // reflect.Type -> bool
+
+func needsRedaction(t reflect.Type) bool {
+	if needs, ok := redactionNeeds.Load(t); ok {
+		return needs.(bool)
+	}
+	needs := typeNeedsRedaction(t, map[reflect.Type]bool{})
+	redactionNeeds.Store(t, needs)
+	return needs
+}
+
+func typeNeedsRedaction(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	if t.Implements(redactableType) || reflect.PointerTo(t).Implements(redactableType) {
+		return true
+	}
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeNeedsRedaction(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if isRedactedField(field) || typeNeedsRedaction(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isRedactedField(field reflect.StructField) bool {
+	for _, option := range strings.Split(field.Tag.Get("restate"), ",") {
+		if option == "redact" {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(value reflect.Value) any {
+	if value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil
+	}
+	if value.CanInterface() {
+		if r, ok := value.Interface().(Redactable); ok {
+			return r.Redacted()
+		}
+		if value.CanAddr() {
+			if r, ok := value.Addr().Interface().(Redactable); ok {
+				return r.Redacted()
+			}
+		}
+	}
+	if !needsRedaction(value.Type()) {
+		return value.Interface()
+	}
+	switch value.Kind() {
+	case reflect.Pointer:
+		return redactValue(value.Elem())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		redacted := make([]any, value.Len())
+		for i := range value.Len() {
+			redacted[i] = redactValue(value.Index(i))
+		}
+		return redacted
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		redacted := reflect.MakeMapWithSize(reflect.MapOf(value.Type().Key(), reflect.TypeFor[any]()), value.Len())
+		for iter := value.MapRange(); iter.Next(); {
+			element := redactValue(iter.Value())
+			if element == nil {
+				redacted.SetMapIndex(iter.Key(), reflect.Zero(reflect.TypeFor[any]()))
+			} else {
+				redacted.SetMapIndex(iter.Key(), reflect.ValueOf(element))
+			}
+		}
+		return redacted.Interface()
+	case reflect.Struct:
+		redacted := make(map[string]any, value.NumField())
+		for i := range value.NumField() {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := fieldName(field)
+			if name == "" {
+				continue
+			}
+			if isRedactedField(field) {
+				redacted[name] = RedactedPlaceholder
+			} else {
+				redacted[name] = redactValue(value.Field(i))
+			}
+		}
+		return redacted
+	}
+	return value.Interface()
+}
+
+// fieldName returns the name of field in the redacted representation of its struct, or "" if
+// it is omitted from its json encoding.
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
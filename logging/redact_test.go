@@ -0,0 +1,55 @@
+package logging_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/restatedev/sdk-go/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password" restate:"redact"`
+	internal string
+}
+
+type account struct {
+	ID          int
+	Credentials *credentials `json:"credentials"`
+	Ignored     string       `json:"-"`
+}
+
+type token string
+
+func (t token) Redacted() any { return "token:" + string(t[:2]) + "..." }
+
+type secretError struct{ secret string }
+
+func (e *secretError) Error() string { return "bad secret " + e.secret }
+func (e *secretError) Redacted() any { return "bad secret" }
+
+func TestRedact(t *testing.T) {
+	require.Equal(t, map[string]any{"user": "ada", "password": logging.RedactedPlaceholder},
+		logging.Redact(credentials{User: "ada", Password: "hunter2", internal: "x"}))
+
+	require.Equal(t, map[string]any{
+		"ID":          1,
+		"credentials": map[string]any{"user": "ada", "password": logging.RedactedPlaceholder},
+	}, logging.Redact(&account{ID: 1, Credentials: &credentials{User: "ada", Password: "hunter2"}, Ignored: "x"}))
+	require.Equal(t, map[string]any{"ID": 2, "credentials": nil}, logging.Redact(account{ID: 2}))
+
+	require.Equal(t, []any{map[string]any{"user": "ada", "password": logging.RedactedPlaceholder}},
+		logging.Redact([]credentials{{User: "ada", Password: "hunter2"}}))
+	require.Equal(t, map[string]any{"ada": "token:se..."}, logging.Redact(map[string]token{"ada": "secret"}))
+
+	require.Equal(t, "token:se...", logging.Redact(token("secret")))
+	require.Equal(t, "bad secret", logging.Redact(fmt.Errorf("login: %w", &secretError{"hunter2"})))
+
+	plain := errors.New("plain")
+	require.Equal(t, plain, logging.Redact(plain))
+	require.Equal(t, struct{ A int }{1}, logging.Redact(struct{ A int }{1}))
+	require.Equal(t, "value", logging.Redact("value"))
+	require.Nil(t, logging.Redact(nil))
+}
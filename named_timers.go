@@ -0,0 +1,168 @@
+package restate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Names of the handlers added by [EnableTimers].
+const (
+	// FireTimerHandlerName is invoked with a delay by [ScheduleTimer] and [Reschedule] to
+	// fire timers.
+	FireTimerHandlerName = "RestateFireTimer"
+	// ListTimersHandlerName returns the pending timers of a key, as a list of [Timer].
+	ListTimersHandlerName = "RestateListTimers"
+)
+
+// timerKeyPrefix prefixes the state keys of the pending timers.
+const timerKeyPrefix = "restate.timer."
+
+// timerVersionKey holds the version of the last timer scheduled on a key. Versions are never
+// reused, so that the delayed invocations of cancelled or rescheduled timers are ignored.
+const timerVersionKey = "restate.timers.version"
+
+// TimerTarget is the handler invoked when a named timer fires. If Service is empty, the
+// handler of the same virtual object and key is invoked.
+type TimerTarget struct {
+	Service string `json:"service,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Handler string `json:"handler"`
+	// Input is the JSON input of the handler. If empty, no input is sent.
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// Timer is a pending named timer.
+type Timer struct {
+	ID     string      `json:"id"`
+	At     time.Time   `json:"at"`
+	Target TimerTarget `json:"target"`
+}
+
+type storedTimer struct {
+	Timer
+	Version uint64 `json:"version"`
+}
+
+type timerFiring struct {
+	ID      string `json:"id"`
+	Version uint64 `json:"version"`
+}
+
+// ScheduleTimer schedules the named timer id of the current key to invoke target at the given
+// time, replacing any pending timer with the same id. Unlike [After], named timers can be
+// moved with [Reschedule] and cancelled with [CancelTimer]. The virtual object must be
+// registered with [EnableTimers].
+func ScheduleTimer(ctx ObjectContext, id string, at time.Time, target TimerTarget) TerminalError {
+	if target.Handler == "" {
+		return ToTerminalError(fmt.Errorf("timer %s: target handler must be set", id), WithErrorCode(http.StatusBadRequest))
+	}
+	if len(target.Input) > 0 && !json.Valid(target.Input) {
+		return ToTerminalError(fmt.Errorf("timer %s: target input is not valid JSON", id), WithErrorCode(http.StatusBadRequest))
+	}
+	return scheduleTimer(ctx, storedTimer{Timer: Timer{ID: id, At: at, Target: target}})
+}
+
+// Reschedule moves the pending named timer id to the given time, failing with a 404 terminal
+// error if there is no such timer.
+func Reschedule(ctx ObjectContext, id string, at time.Time) TerminalError {
+	timer, err := Get[*storedTimer](ctx, timerKeyPrefix+id)
+	if err != nil {
+		return err
+	}
+	if timer == nil {
+		return ToTerminalError(fmt.Errorf("timer %s not found", id), WithErrorCode(http.StatusNotFound))
+	}
+	timer.At = at
+	return scheduleTimer(ctx, *timer)
+}
+
+// CancelTimer cancels the pending named timer id, returning false if there is no such timer.
+func CancelTimer(ctx ObjectContext, id string) (bool, TerminalError) {
+	timer, err := Get[*storedTimer](ctx, timerKeyPrefix+id)
+	if err != nil || timer == nil {
+		return false, err
+	}
+	// The delayed invocation still happens, and is ignored
+	Clear(ctx, timerKeyPrefix+id)
+	return true, nil
+}
+
+// ListTimers returns the pending named timers of the current key, ordered by time.
+func ListTimers(ctx ObjectSharedContext) ([]Timer, TerminalError) {
+	keys, err := Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timers := []Timer{}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, timerKeyPrefix) {
+			continue
+		}
+		timer, err := Get[storedTimer](ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		timers = append(timers, timer.Timer)
+	}
+	slices.SortStableFunc(timers, func(a, b Timer) int {
+		return a.At.Compare(b.At)
+	})
+	return timers, nil
+}
+
+func scheduleTimer(ctx ObjectContext, timer storedTimer) TerminalError {
+	now, err := now(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := Get[uint64](ctx, timerVersionKey)
+	if err != nil {
+		return err
+	}
+	timer.Version = version + 1
+	Set(ctx, timerVersionKey, timer.Version)
+	Set(ctx, timerKeyPrefix+timer.ID, timer)
+	ObjectSend(ctx, ctx.Request().Service, Key(ctx), FireTimerHandlerName).
+		Send(timerFiring{ID: timer.ID, Version: timer.Version}, WithDelay(max(0, timer.At.Sub(now))))
+	return nil
+}
+
+// EnableTimers adds to the virtual object definition the handlers firing and listing the
+// named timers scheduled with [ScheduleTimer], and returns the definition. It panics if
+// definition is not a virtual object created with [NewObject] or [Reflect].
+func EnableTimers(definition ServiceDefinition) ServiceDefinition {
+	def, ok := definition.(*object)
+	if !ok {
+		panic(fmt.Sprintf("timers can only be enabled on virtual objects, %s is a %s", definition.Name(), definition.Type()))
+	}
+	return def.
+		Handler(FireTimerHandlerName, NewObjectHandler(fireTimer, WithJSON)).
+		Handler(ListTimersHandlerName, NewObjectSharedHandler(listTimers, WithJSON))
+}
+
+func fireTimer(ctx ObjectContext, firing timerFiring) (Void, error) {
+	timer, err := Get[*storedTimer](ctx, timerKeyPrefix+firing.ID)
+	if err != nil {
+		return Void{}, err
+	}
+	if timer == nil || timer.Version != firing.Version {
+		// The timer was cancelled or rescheduled
+		return Void{}, nil
+	}
+
+	Clear(ctx, timerKeyPrefix+firing.ID)
+	target := timer.Target
+	if target.Service == "" {
+		target.Service, target.Key = ctx.Request().Service, Key(ctx)
+	}
+	sendRaw(ctx, target.Service, target.Key, target.Handler, target.Input)
+	return Void{}, nil
+}
+
+func listTimers(ctx ObjectSharedContext, _ Void) ([]Timer, error) {
+	return ListTimers(ctx)
+}
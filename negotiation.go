@@ -0,0 +1,180 @@
+package restate
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/restatedev/sdk-go/internal/restatecontext"
+)
+
+type withAcceptedCodecs struct {
+	codecs []encoding.Codec
+}
+
+var _ options.HandlerOption = withAcceptedCodecs{}
+
+func (w withAcceptedCodecs) BeforeHandler(opts *options.HandlerOptions) {
+	opts.AcceptedCodecs = append(opts.AcceptedCodecs, w.codecs...)
+}
+
+// WithAcceptedCodecs lets a handler accept inputs and produce outputs encoded with codecs, in
+// addition to its input and output codecs. Each invocation is decoded with the codec matching
+// the Content-Type header of the request, and its output is encoded with the codec preferred by
+// the Accept header. The handler's own codecs are used when the headers are missing.
+// Invocations with a Content-Type or an Accept header that no codec matches fail with a
+// terminal error with code 415 or 406.
+//
+// Codecs are matched by the content type they advertise with [encoding.CodecMetadata]. The
+// input payload advertised for discovery lists the content types of all the codecs, and
+// describes the schema of the handler's input codec. The ingress sets the Content-Type of
+// responses to the one of the handler's output codec.
+//
+//	restate.NewServiceHandler(handler, restate.WithAcceptedCodecs(encoding.ProtoCodec, encoding.CBORCodec))
+func WithAcceptedCodecs(codecs ...encoding.Codec) withAcceptedCodecs {
+	return withAcceptedCodecs{codecs}
+}
+
+// handlerCodecs returns the codecs decoding the input and encoding the output of the
+// invocation of a handler, negotiated with the request headers.
+func handlerCodecs(ctx restatecontext.Context, o *options.HandlerOptions) (encoding.Codec, encoding.Codec, error) {
+	if len(o.AcceptedCodecs) == 0 {
+		return o.InputCodec, o.OutputCodec, nil
+	}
+	var contentType, accept string
+	if request := ctx.Request(); request != nil && request.Headers != nil {
+		for key, value := range request.Headers.Iter() {
+			switch strings.ToLower(key) {
+			case "content-type":
+				contentType = value
+			case "accept":
+				accept = value
+			}
+		}
+	}
+
+	inputCodec, err := negotiateInputCodec(contentType, o.InputCodec, o.AcceptedCodecs)
+	if err != nil {
+		return nil, nil, err
+	}
+	outputCodec, err := negotiateOutputCodec(accept, o.OutputCodec, o.AcceptedCodecs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return inputCodec, outputCodec, nil
+}
+
+func negotiateInputCodec(contentType string, defaultCodec encoding.Codec, accepted []encoding.Codec) (encoding.Codec, error) {
+	if contentType == "" {
+		return defaultCodec, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ToTerminalError(fmt.Errorf("invalid content type %q: %w", contentType, err), WithErrorCode(http.StatusUnsupportedMediaType))
+	}
+	for _, codec := range append([]encoding.Codec{defaultCodec}, accepted...) {
+		if codecContentType(codec) == mediaType {
+			return codec, nil
+		}
+	}
+	return nil, ToTerminalError(
+		fmt.Errorf("unsupported content type %s, expected one of %s", mediaType, strings.Join(codecContentTypes(defaultCodec, accepted), ", ")),
+		WithErrorCode(http.StatusUnsupportedMediaType),
+	)
+}
+
+func negotiateOutputCodec(accept string, defaultCodec encoding.Codec, accepted []encoding.Codec) (encoding.Codec, error) {
+	if strings.TrimSpace(accept) == "" {
+		return defaultCodec, nil
+	}
+	codecs := append([]encoding.Codec{defaultCodec}, accepted...)
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType, quality})
+		}
+	}
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	for _, r := range ranges {
+		for _, codec := range codecs {
+			if mediaRangeMatches(r.mediaType, codecContentType(codec)) {
+				return codec, nil
+			}
+		}
+	}
+	return nil, ToTerminalError(
+		fmt.Errorf("no acceptable content type for %q, can produce %s", accept, strings.Join(codecContentTypes(defaultCodec, accepted), ", ")),
+		WithErrorCode(http.StatusNotAcceptable),
+	)
+}
+
+func mediaRangeMatches(mediaRange string, contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	if mediaRange == "*/*" || mediaRange == contentType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(contentType, prefix+"/")
+}
+
+func codecContentType(codec encoding.Codec) string {
+	if m, ok := codec.(encoding.CodecMetadata); ok {
+		return m.ContentType()
+	}
+	return ""
+}
+
+func codecContentTypes(defaultCodec encoding.Codec, accepted []encoding.Codec) []string {
+	var contentTypes []string
+	for _, codec := range append([]encoding.Codec{defaultCodec}, accepted...) {
+		if contentType := codecContentType(codec); contentType != "" && !slices.Contains(contentTypes, contentType) {
+			contentTypes = append(contentTypes, contentType)
+		}
+	}
+	return contentTypes
+}
+
+// handlerInputPayload describes the input of a handler for discovery, accepting the content
+// types of all its codecs.
+func handlerInputPayload(o *options.HandlerOptions, i any) *encoding.InputPayload {
+	payload := encoding.InputPayloadFor(o.InputCodec, i)
+	if len(o.AcceptedCodecs) == 0 || payload.ContentType == nil {
+		return payload
+	}
+	// discovery takes the accepted content types in the format of an Accept header
+	contentTypes := strings.Join(codecContentTypes(o.InputCodec, o.AcceptedCodecs), ", ")
+	payload.ContentType = &contentTypes
+	return payload
+}
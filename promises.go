@@ -11,7 +11,7 @@ type PromiseOption = options.PromiseOption
 // Promise returns a named Restate durable Promise that can be resolved or rejected during the workflow execution.
 // The promise is bound to the workflow and will be persisted across suspensions and retries.
 func Promise[T any](ctx WorkflowSharedContext, name string, options ...options.PromiseOption) DurablePromise[T] {
-	return durablePromise[T]{ctx.inner().Promise(name, options...)}
+	return durablePromise[T]{ctx.inner().Promise(name, withRegisteredCodec[T](options)...)}
 }
 
 type DurablePromise[T any] interface {
@@ -0,0 +1,401 @@
+package restate
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/restatedev/sdk-go/internal/options"
+)
+
+// RateLimiterServiceName is the name of the virtual object returned by
+// [NewRateLimiterService]. Each key of the object is a rate limiter.
+const RateLimiterServiceName = "RestateRateLimiter"
+
+// RateLimitPolicy is the algorithm a rate limiter uses to admit requests.
+type RateLimitPolicy string
+
+const (
+	// RateLimitTokenBucket refills a bucket of Burst tokens at Rate tokens per second, and
+	// admits a request when enough tokens are left. Bursts of up to Burst tokens are admitted
+	// at once.
+	RateLimitTokenBucket RateLimitPolicy = "token_bucket"
+	// RateLimitLeakyBucket admits requests one after the other at Rate tokens per second,
+	// smoothing bursts: a request is only admitted once the ones admitted before it have
+	// leaked out of the bucket. Burst is ignored.
+	RateLimitLeakyBucket RateLimitPolicy = "leaky_bucket"
+)
+
+// RateLimitConfig configures a rate limiter, through the Configure handler of the object
+// returned by [NewRateLimiterService] or with [ConfigureRateLimiter].
+type RateLimitConfig struct {
+	// Policy defaults to [RateLimitTokenBucket].
+	Policy RateLimitPolicy `json:"policy,omitempty"`
+	// Rate is the number of tokens per second.
+	Rate float64 `json:"rate"`
+	// Burst is the capacity of the token bucket.
+	Burst float64 `json:"burst,omitempty"`
+}
+
+func (c RateLimitConfig) validate() error {
+	switch c.Policy {
+	case RateLimitTokenBucket:
+		if c.Burst <= 0 {
+			return fmt.Errorf("burst must be positive")
+		}
+	case RateLimitLeakyBucket:
+	default:
+		return fmt.Errorf("unknown policy %q", c.Policy)
+	}
+	if c.Rate <= 0 || math.IsInf(c.Rate, 0) || math.IsNaN(c.Rate) {
+		return fmt.Errorf("rate must be positive")
+	}
+	return nil
+}
+
+// RateLimitStatus is the state of a rate limiter, as returned by the Status handler of the
+// object returned by [NewRateLimiterService].
+type RateLimitStatus struct {
+	Config RateLimitConfig `json:"config"`
+	// Tokens left in the bucket, for [RateLimitTokenBucket].
+	Tokens float64 `json:"tokens"`
+	// NextFree is the time at which the next request is admitted, for [RateLimitLeakyBucket].
+	NextFree time.Time `json:"nextFree,omitzero"`
+	// Waiting is the number of blocked requests.
+	Waiting int `json:"waiting"`
+}
+
+type rateLimitBucket struct {
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	NextFree  time.Time `json:"nextFree,omitzero"`
+	// WakeAt is the time of the last wake up scheduled for the queue.
+	WakeAt time.Time `json:"wakeAt,omitzero"`
+}
+
+type rateLimitRequest struct {
+	AwakeableID string `json:"awakeableId,omitempty"`
+	Cost        int    `json:"cost"`
+}
+
+var (
+	rateLimitConfig      = NewStateKey[*RateLimitConfig]("config")
+	rateLimitBucketState = NewStateKey[*rateLimitBucket]("bucket")
+	rateLimitQueue       = NewStateKey[[]rateLimitRequest]("queue")
+)
+
+// RateLimit blocks until the rate limiter with the given name admits a request costing cost
+// tokens. It fails with a terminal error if the limiter is not configured, or if cost exceeds
+// the burst of a token bucket.
+//
+// Rate limiters are implemented by the virtual object returned by [NewRateLimiterService],
+// which must be bound to a server of the same Restate deployment.
+func RateLimit(ctx Context, name string, cost int) TerminalError {
+	awakeable := Awakeable[Void](ctx)
+	ObjectSend(ctx, RateLimiterServiceName, name, "Acquire").
+		Send(rateLimitRequest{AwakeableID: awakeable.Id(), Cost: cost})
+	_, err := awakeable.Result()
+	return err
+}
+
+// TryAcquire asks the rate limiter with the given name to admit a request costing cost
+// tokens, without waiting for tokens to be available. It returns false if the request was
+// not admitted.
+func TryAcquire(ctx Context, name string, cost int) (bool, TerminalError) {
+	return Object[bool](ctx, RateLimiterServiceName, name, "TryAcquire").Request(rateLimitRequest{Cost: cost})
+}
+
+// ConfigureRateLimiter sets the configuration of the rate limiter with the given name. The
+// tokens of an existing bucket are kept, up to the new burst.
+func ConfigureRateLimiter(ctx Context, name string, config RateLimitConfig) TerminalError {
+	_, err := Object[Void](ctx, RateLimiterServiceName, name, "Configure").Request(config)
+	return err
+}
+
+// NewRateLimiterService returns the virtual object implementing [RateLimit] and
+// [TryAcquire], named [RateLimiterServiceName]. Bind it once to a server of the Restate
+// deployment:
+//
+//	server.NewRestate().Bind(restate.NewRateLimiterService())
+//
+// Limiters are configured at runtime with the Configure handler, taking a [RateLimitConfig],
+// and inspected with the shared Status handler, returning a [RateLimitStatus]. Time is
+// recorded in the journal, so the admission decisions are deterministic on replay.
+func NewRateLimiterService(opts ...options.ServiceDefinitionOption) ServiceDefinition {
+	return NewObject(RateLimiterServiceName, append([]options.ServiceDefinitionOption{
+		WithDocumentation("Durable rate limiters, keyed by name."),
+		WithStateKeys(rateLimitConfig, rateLimitBucketState, rateLimitQueue),
+	}, opts...)...).
+		Handler("Acquire", NewObjectHandler(rateLimitAcquire)).
+		Handler("TryAcquire", NewObjectHandler(rateLimitTryAcquire)).
+		Handler("Wake", NewObjectHandler(rateLimitWake)).
+		Handler("Grant", NewObjectSharedHandler(rateLimitGrant)).
+		Handler("Configure", NewObjectHandler(rateLimitConfigure)).
+		Handler("Status", NewObjectSharedHandler(rateLimitStatus))
+}
+
+// rateLimiter is the state of a rate limiter loaded by an exclusive handler, refilled up
+// to the current time.
+type rateLimiter struct {
+	config RateLimitConfig
+	bucket rateLimitBucket
+	now    time.Time
+}
+
+// loadRateLimiter returns nil if the limiter is not configured, and the errors reading its
+// state otherwise.
+func loadRateLimiter(ctx ObjectContext) (*rateLimiter, TerminalError) {
+	config, err := rateLimitConfig.Get(ctx)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	bucket, err := rateLimitBucketState.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := now(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bucket == nil {
+		bucket = &rateLimitBucket{Tokens: config.Burst, UpdatedAt: now}
+	}
+
+	l := &rateLimiter{config: *config, bucket: *bucket, now: now}
+	if elapsed := now.Sub(l.bucket.UpdatedAt); elapsed > 0 {
+		l.bucket.Tokens = min(l.config.Burst, l.bucket.Tokens+elapsed.Seconds()*l.config.Rate)
+		l.bucket.UpdatedAt = now
+	}
+	return l, nil
+}
+
+func rateLimiterNotConfigured(ctx ObjectSharedContext) TerminalError {
+	return ToTerminalError(fmt.Errorf("rate limiter %s is not configured", Key(ctx)), WithErrorCode(http.StatusNotFound))
+}
+
+func (l *rateLimiter) save(ctx ObjectContext) {
+	rateLimitBucketState.Set(ctx, &l.bucket)
+}
+
+func (l *rateLimiter) checkCost(ctx ObjectContext, cost int) error {
+	if cost <= 0 {
+		return fmt.Errorf("rate limiter %s: cost must be positive", Key(ctx))
+	}
+	if l.config.Policy == RateLimitTokenBucket && float64(cost) > l.config.Burst {
+		return fmt.Errorf("rate limiter %s: cost %d exceeds burst %v", Key(ctx), cost, l.config.Burst)
+	}
+	return nil
+}
+
+// take admits a request right away if possible.
+func (l *rateLimiter) take(cost int) bool {
+	if l.config.Policy == RateLimitLeakyBucket {
+		if l.bucket.NextFree.After(l.now) {
+			return false
+		}
+		l.bucket.NextFree = l.now.Add(l.duration(float64(cost)))
+		return true
+	}
+	if l.bucket.Tokens < float64(cost) {
+		return false
+	}
+	l.bucket.Tokens -= float64(cost)
+	return true
+}
+
+// duration returns the time it takes to accumulate the given tokens.
+func (l *rateLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.config.Rate * float64(time.Second)))
+}
+
+func rateLimitAcquire(ctx ObjectContext, req rateLimitRequest) (Void, error) {
+	l, err := loadRateLimiter(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if l == nil {
+		RejectAwakeable(ctx, req.AwakeableID, rateLimiterNotConfigured(ctx))
+		return Void{}, nil
+	}
+	if err := l.checkCost(ctx, req.Cost); err != nil {
+		RejectAwakeable(ctx, req.AwakeableID, ToTerminalError(err, WithErrorCode(http.StatusBadRequest)))
+		return Void{}, nil
+	}
+
+	if l.config.Policy == RateLimitLeakyBucket {
+		// Every request gets the next slot, resolved with a delay if it is in the future
+		start := l.bucket.NextFree
+		if start.Before(l.now) {
+			start = l.now
+		}
+		l.bucket.NextFree = start.Add(l.duration(float64(req.Cost)))
+		if delay := start.Sub(l.now); delay > 0 {
+			ObjectSend(ctx, RateLimiterServiceName, Key(ctx), "Grant").Send(req, WithDelay(delay))
+		} else {
+			ResolveAwakeable(ctx, req.AwakeableID, Void{})
+		}
+		l.save(ctx)
+		return Void{}, nil
+	}
+
+	queue, err := rateLimitQueue.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if len(queue) == 0 && l.take(req.Cost) {
+		ResolveAwakeable(ctx, req.AwakeableID, Void{})
+		l.save(ctx)
+		return Void{}, nil
+	}
+	rateLimitQueue.Set(ctx, append(queue, req))
+	if len(queue) == 0 {
+		// Otherwise a wake up is already scheduled for the head of the queue
+		l.scheduleWake(ctx, req.Cost)
+	}
+	l.save(ctx)
+	return Void{}, nil
+}
+
+// scheduleWake wakes up the limiter once the request at the head of the queue can be
+// admitted, unless a wake up is already scheduled by then.
+func (l *rateLimiter) scheduleWake(ctx ObjectContext, cost int) {
+	var delay time.Duration
+	switch {
+	case l.checkCost(ctx, cost) != nil:
+		// Rejected right away
+	case l.config.Policy == RateLimitLeakyBucket:
+		// The queue is left over from a token bucket reconfigured as a leaky bucket
+		delay = max(0, l.bucket.NextFree.Sub(l.now))
+	default:
+		delay = l.duration(float64(cost) - l.bucket.Tokens)
+	}
+	wakeAt := l.now.Add(delay)
+	if l.bucket.WakeAt.After(l.now) && !l.bucket.WakeAt.After(wakeAt) {
+		return
+	}
+	l.bucket.WakeAt = wakeAt
+	ObjectSend(ctx, RateLimiterServiceName, Key(ctx), "Wake").Send(Void{}, WithDelay(delay))
+}
+
+// rateLimitWake admits the waiting requests of a token bucket, in FIFO order, once tokens
+// have been refilled.
+func rateLimitWake(ctx ObjectContext, _ Void) (Void, error) {
+	queue, err := rateLimitQueue.Get(ctx)
+	if err != nil || len(queue) == 0 {
+		return Void{}, err
+	}
+	l, err := loadRateLimiter(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if l == nil {
+		return Void{}, rateLimiterNotConfigured(ctx)
+	}
+
+	for len(queue) > 0 {
+		if err := l.checkCost(ctx, queue[0].Cost); err != nil {
+			// The limiter was reconfigured with a lower burst
+			RejectAwakeable(ctx, queue[0].AwakeableID, ToTerminalError(err, WithErrorCode(http.StatusBadRequest)))
+		} else if l.take(queue[0].Cost) {
+			ResolveAwakeable(ctx, queue[0].AwakeableID, Void{})
+		} else {
+			break
+		}
+		queue = queue[1:]
+	}
+
+	if len(queue) == 0 {
+		rateLimitQueue.Clear(ctx)
+	} else {
+		rateLimitQueue.Set(ctx, queue)
+		l.scheduleWake(ctx, queue[0].Cost)
+	}
+	l.save(ctx)
+	return Void{}, nil
+}
+
+// rateLimitGrant admits a request of a leaky bucket once its slot is reached.
+func rateLimitGrant(ctx ObjectSharedContext, req rateLimitRequest) (Void, error) {
+	ResolveAwakeable(ctx, req.AwakeableID, Void{})
+	return Void{}, nil
+}
+
+func rateLimitTryAcquire(ctx ObjectContext, req rateLimitRequest) (bool, error) {
+	l, err := loadRateLimiter(ctx)
+	if err != nil {
+		return false, err
+	}
+	if l == nil {
+		return false, rateLimiterNotConfigured(ctx)
+	}
+	if err := l.checkCost(ctx, req.Cost); err != nil {
+		return false, ToTerminalError(err, WithErrorCode(http.StatusBadRequest))
+	}
+	queue, err := rateLimitQueue.Get(ctx)
+	if err != nil {
+		return false, err
+	}
+	// Blocked requests take precedence
+	if len(queue) > 0 || !l.take(req.Cost) {
+		return false, nil
+	}
+	l.save(ctx)
+	return true, nil
+}
+
+func rateLimitConfigure(ctx ObjectContext, config RateLimitConfig) (Void, error) {
+	if config.Policy == "" {
+		config.Policy = RateLimitTokenBucket
+	}
+	if err := config.validate(); err != nil {
+		return Void{}, ToTerminalError(fmt.Errorf("rate limiter %s: %w", Key(ctx), err), WithErrorCode(http.StatusBadRequest))
+	}
+
+	// Account for the tokens refilled with the previous configuration
+	l, err := loadRateLimiter(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	rateLimitConfig.Set(ctx, &config)
+	if l == nil {
+		return Void{}, nil
+	}
+	l.config = config
+	l.bucket.Tokens = min(l.bucket.Tokens, config.Burst)
+
+	queue, err := rateLimitQueue.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if len(queue) > 0 {
+		// Wake up earlier if the waiters are admitted sooner with the new configuration
+		l.scheduleWake(ctx, queue[0].Cost)
+	}
+	l.save(ctx)
+	return Void{}, nil
+}
+
+func rateLimitStatus(ctx ObjectSharedContext, _ Void) (RateLimitStatus, error) {
+	config, err := rateLimitConfig.Get(ctx)
+	if err != nil {
+		return RateLimitStatus{}, err
+	}
+	if config == nil {
+		return RateLimitStatus{}, rateLimiterNotConfigured(ctx)
+	}
+	bucket, err := rateLimitBucketState.Get(ctx)
+	if err != nil {
+		return RateLimitStatus{}, err
+	}
+	queue, err := rateLimitQueue.Get(ctx)
+	if err != nil {
+		return RateLimitStatus{}, err
+	}
+	status := RateLimitStatus{Config: *config, Tokens: config.Burst, Waiting: len(queue)}
+	if bucket != nil {
+		status.Tokens = bucket.Tokens
+		status.NextFree = bucket.NextFree
+	}
+	return status, nil
+}
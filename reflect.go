@@ -53,9 +53,9 @@ func acceptedContextParameterString(serviceType internal.ServiceType) string {
 // This function will panic if a mixture of object service and workflow method signatures or opts are provided, or if multiple WorkflowContext
 // methods are defined.
 //
-// Input types will be deserialised with the provided codec (defaults to JSON) except when they are [Void],
+// Input types will be deserialised with the provided codec (defaults to the codec registered for the type with [encoding.Register], or JSON) except when they are [Void],
 // in which case no input bytes or content type may be sent.
-// Output types will be serialised with the provided codec (defaults to JSON) except when they are [Void],
+// Output types will be serialised with the provided codec (defaults to the codec registered for the type with [encoding.Register], or JSON) except when they are [Void],
 // in which case no data will be sent and no content type set.
 func Reflect(rcvr any, opts ...options.ServiceDefinitionOption) ServiceDefinition {
 	typ := reflect.TypeOf(rcvr)
@@ -244,11 +244,15 @@ func (h *reflectHandler) GetOptions() *options.HandlerOptions {
 	return &h.options
 }
 
+func (h *reflectHandler) registeredCodecs() (encoding.Codec, encoding.Codec) {
+	return encoding.RegisteredCodec(h.input), encoding.RegisteredCodec(h.output)
+}
+
 func (h *reflectHandler) InputPayload() *encoding.InputPayload {
 	if h.input == nil {
-		return encoding.InputPayloadFor(h.options.InputCodec, Void{})
+		return handlerInputPayload(&h.options, Void{})
 	}
-	return encoding.InputPayloadFor(h.options.InputCodec, reflect.Zero(h.input).Interface())
+	return handlerInputPayload(&h.options, reflect.Zero(h.input).Interface())
 }
 
 func (h *reflectHandler) OutputPayload() *encoding.OutputPayload {
@@ -263,14 +267,22 @@ func (h *reflectHandler) HandlerType() *internal.ServiceHandlerType {
 }
 
 func (h *reflectHandler) Call(ctx restatecontext.Context, bytes []byte) ([]byte, error) {
+	ctx = withStateTTL(ctx, &h.options)
+	inputCodec, outputCodec, err := handlerCodecs(ctx, &h.options)
+	if err != nil {
+		return nil, err
+	}
 
 	var args []reflect.Value
 	if h.input != nil {
 		input := reflect.New(h.input)
 
-		if err := encoding.Unmarshal(h.options.InputCodec, bytes, input.Interface()); err != nil {
+		if err := encoding.Unmarshal(inputCodec, bytes, input.Interface()); err != nil {
 			return nil, ToTerminalError(fmt.Errorf("request could not be decoded into handler input type: %v", err), WithErrorCode(http.StatusBadRequest))
 		}
+		if err := validateInput(h.options.Validator, input.Interface()); err != nil {
+			return nil, err
+		}
 
 		args = []reflect.Value{h.receiver, reflect.ValueOf(ctxWrapper{ctx}), input.Elem()}
 	} else {
@@ -303,7 +315,7 @@ func (h *reflectHandler) Call(ctx restatecontext.Context, bytes []byte) ([]byte,
 		outI = output[0].Interface()
 	}
 
-	bytes, err := encoding.Marshal(h.options.OutputCodec, outI)
+	bytes, err = encoding.Marshal(outputCodec, outI)
 	if err != nil {
 		// we don't use a terminal error here as this is hot-fixable by changing the return type
 		return nil, fmt.Errorf("failed to serialize output: %w", err)
@@ -115,6 +115,37 @@ func WithMaxRetryDuration(d time.Duration) withMaxRetryDuration {
 	return withMaxRetryDuration{d}
 }
 
+type withRetryIf struct{ retryIf func(error) bool }
+
+var _ options.RunOption = withRetryIf{}
+
+func (w withRetryIf) BeforeRun(o *options.RunOptions) {
+	o.RetryIf = w.retryIf
+}
+
+// WithRetryIf retries the failures of a Run function only if retryIf returns true. The other
+// failures fail the Run function right away, as terminal errors keeping the code of a
+// [RetryableError]. Terminal errors are never retried.
+func WithRetryIf(retryIf func(error) bool) withRetryIf {
+	return withRetryIf{retryIf}
+}
+
+type withRetryJitter struct{ fraction float64 }
+
+var _ options.RunOption = withRetryJitter{}
+
+func (w withRetryJitter) BeforeRun(o *options.RunOptions) {
+	o.RetryJitter = w.fraction
+}
+
+// WithRetryJitter randomizes each retry interval of a Run function by up to the given
+// fraction, between 0 and 1, so that concurrent invocations don't retry in lockstep. For
+// example 0.2 picks intervals between 80% and 120% of the computed one. Delays suggested with
+// [WithRetryAfter] are only lengthened.
+func WithRetryJitter(fraction float64) withRetryJitter {
+	return withRetryJitter{fraction}
+}
+
 type withOnMaxAttempts struct{ v options.OnMaxAttempts }
 
 var _ options.InvocationRetryPolicyOption = withOnMaxAttempts{}
@@ -0,0 +1,40 @@
+package restate_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	delay, ok := restate.ParseRetryAfter("120", now)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Minute, delay)
+
+	delay, ok = restate.ParseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	require.Equal(t, 30*time.Second, delay)
+
+	delay, ok = restate.ParseRetryAfter(now.Add(-time.Hour).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	require.Zero(t, delay)
+
+	for _, invalid := range []string{"", "-1", "soon"} {
+		_, ok = restate.ParseRetryAfter(invalid, now)
+		require.False(t, ok, invalid)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := restate.ToRetryableError(errors.New("rate limited"), restate.WithErrorCode(429), restate.WithRetryAfter(5*time.Second))
+	require.Equal(t, restate.Code(429), err.Code())
+	require.Equal(t, 5*time.Second, restate.AsRetryableError(err).RetryAfter())
+
+	require.Zero(t, restate.RetryableErrorf("unavailable").RetryAfter())
+}
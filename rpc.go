@@ -23,7 +23,7 @@ type AttachOption = options.AttachOption
 
 // Service gets a Service request client by service and method name
 func Service[O any](ctx Context, service string, method string, options ...options.ClientOption) Client[any, O] {
-	return outputClient[O]{ctx.inner().Service(service, method, options...)}
+	return outputClient[O]{ctx.inner().Service(service, method, withRegisteredOutputCodec[O](options)...)}
 }
 
 // ServiceSend gets a Service send client by service and method name
@@ -33,7 +33,7 @@ func ServiceSend(ctx Context, service string, method string, options ...options.
 
 // Object gets an Object request client by service name, key and method name
 func Object[O any](ctx Context, service string, key string, method string, options ...options.ClientOption) Client[any, O] {
-	return outputClient[O]{ctx.inner().Object(service, key, method, options...)}
+	return outputClient[O]{ctx.inner().Object(service, key, method, withRegisteredOutputCodec[O](options)...)}
 }
 
 // ObjectSend gets an Object send client by service name, key and method name
@@ -43,7 +43,7 @@ func ObjectSend(ctx Context, service string, key string, method string, options
 
 // Workflow gets a Workflow request client by service name, workflow ID and method name
 func Workflow[O any](ctx Context, service string, workflowID string, method string, options ...options.ClientOption) Client[any, O] {
-	return outputClient[O]{ctx.inner().Workflow(service, workflowID, method, options...)}
+	return outputClient[O]{ctx.inner().Workflow(service, workflowID, method, withRegisteredOutputCodec[O](options)...)}
 }
 
 // WorkflowSend gets a Workflow send client by service name, workflow ID and method name
@@ -136,7 +136,7 @@ type AttachFuture[O any] interface {
 
 // AttachInvocation attaches to the invocation with the given invocation id.
 func AttachInvocation[T any](ctx Context, invocationId string, options ...options.AttachOption) AttachFuture[T] {
-	return genericfutures.AttachFuture[T]{AttachFuture: ctx.inner().AttachInvocation(invocationId, options...)}
+	return genericfutures.AttachFuture[T]{AttachFuture: ctx.inner().AttachInvocation(invocationId, withRegisteredCodec[T](options)...)}
 }
 
 type withScope struct {
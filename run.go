@@ -49,9 +49,15 @@ type RunOption = options.RunOption
 //		return result, err
 //	}
 func Run[T any](ctx Context, fn func(ctx RunContext) (T, error), options ...options.RunOption) (output T, err TerminalError) {
-	err = ctx.inner().Run(func(ctx RunContext) (any, error) {
+	options = withRegisteredCodec[T](options)
+	run := func(ctx RunContext) (any, error) {
 		return fn(ctx)
-	}, &output, options...)
+	}
+	if o := runOptions(options); o.CircuitBreaker != "" {
+		err = runWithCircuitBreaker(ctx, run, &output, o, options)
+		return
+	}
+	err = ctx.inner().Run(run, &output, options...)
 
 	return
 }
@@ -67,6 +73,11 @@ func Run[T any](ctx Context, fn func(ctx RunContext) (T, error), options ...opti
 // IMPORTANT: Only use the RunContext parameter provided to the function, NOT the
 // handler's Context. See the Run function documentation for detailed examples and guidelines.
 func RunAsync[T any](ctx Context, fn func(ctx RunContext) (T, error), options ...options.RunOption) RunAsyncFuture[T] {
+	options = withRegisteredCodec[T](options)
+	if runOptions(options).CircuitBreaker != "" {
+		// panic because this is a programming error
+		panic("circuit breakers are not supported by RunAsync")
+	}
 	return genericfutures.RunAsyncFuture[T]{RunAsyncFuture: ctx.inner().RunAsync(func(ctx RunContext) (any, error) {
 		return fn(ctx)
 	}, options...)}
@@ -84,11 +95,21 @@ func RunAsync[T any](ctx Context, fn func(ctx RunContext) (T, error), options ..
 // handler's Context. See the Run function documentation for detailed examples and guidelines.
 func RunVoid(ctx Context, fn func(ctx RunContext) error, options ...options.RunOption) TerminalError {
 	var output Void
-	err := ctx.inner().Run(func(ctx RunContext) (any, error) {
+	run := func(ctx RunContext) (any, error) {
 		return nil, fn(ctx)
-	}, &output, options...)
+	}
+	if o := runOptions(options); o.CircuitBreaker != "" {
+		return runWithCircuitBreaker(ctx, run, &output, o, options)
+	}
+	return ctx.inner().Run(run, &output, options...)
+}
 
-	return err
+func runOptions(opts []options.RunOption) options.RunOptions {
+	o := options.RunOptions{}
+	for _, opt := range opts {
+		opt.BeforeRun(&o)
+	}
+	return o
 }
 
 // RunAsyncFuture is a 'promise' for a RunAsync operation.
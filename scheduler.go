@@ -0,0 +1,410 @@
+package restate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/restatedev/sdk-go/internal/cron"
+	"github.com/restatedev/sdk-go/internal/options"
+)
+
+// SchedulerServiceName is the name of the virtual object returned by [NewSchedulerService].
+// Each key of the object is a recurring job.
+const SchedulerServiceName = "RestateScheduler"
+
+// schedulerIndexKey is the key of the scheduler object listing the jobs, which is not a
+// valid job ID.
+const schedulerIndexKey = ""
+
+// maxCatchUpRuns bounds the missed runs a job with [MissedRunsCatchUp] performs at once;
+// further missed runs are skipped.
+const maxCatchUpRuns = 100
+
+// MissedRunPolicy decides what a job does with the runs it missed, while paused or when a
+// run is late.
+type MissedRunPolicy string
+
+const (
+	// MissedRunsSkip runs the job once for all the runs it missed. It is the default.
+	MissedRunsSkip MissedRunPolicy = "skip"
+	// MissedRunsCatchUp runs the job once for each run it missed, up to 100 runs; further
+	// missed runs are skipped.
+	MissedRunsCatchUp MissedRunPolicy = "catch_up"
+)
+
+// Headers sent with each run of a job to its target.
+const (
+	JobIDHeader          = "x-restate-job-id"
+	JobScheduledAtHeader = "x-restate-job-scheduled-at"
+)
+
+// JobTarget is the handler invoked by a job. Key is the key of a virtual object or the ID of
+// a workflow, and is left empty for services.
+type JobTarget struct {
+	Service string `json:"service"`
+	Key     string `json:"key,omitempty"`
+	Handler string `json:"handler"`
+}
+
+// Job is a recurring job, run either on a cron schedule or at a fixed interval.
+type Job struct {
+	// Cron is a 5-field cron expression, such as "*/15 9-17 * * mon-fri" or "@daily".
+	Cron string `json:"cron,omitempty"`
+	// Every is the interval between runs, as parsed by [time.ParseDuration], counted from the
+	// time the job is scheduled. Exactly one of Cron and Every must be set.
+	Every string `json:"every,omitempty"`
+	// TimeZone is the IANA time zone in which Cron is evaluated, UTC by default.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	Target JobTarget `json:"target"`
+	// Input is the JSON input sent to Target on each run. If empty, no input is sent.
+	Input json.RawMessage `json:"input,omitempty"`
+
+	MissedRuns MissedRunPolicy `json:"missedRuns,omitempty"`
+}
+
+// JobStatus is the state of a job, as returned by [GetJob].
+type JobStatus struct {
+	Job    Job  `json:"job"`
+	Paused bool `json:"paused"`
+	// NextRunAt is the time of the next run. While paused, it is the first run missed.
+	NextRunAt time.Time `json:"nextRunAt,omitzero"`
+	// LastRunAt is the scheduled time of the last run.
+	LastRunAt time.Time `json:"lastRunAt,omitzero"`
+	Runs      uint64    `json:"runs"`
+}
+
+type jobState struct {
+	JobStatus
+	// Anchor is the time the job was scheduled, from which intervals are counted.
+	Anchor time.Time `json:"anchor"`
+	// Generation is incremented whenever the pending run is superseded.
+	Generation uint64 `json:"generation"`
+}
+
+type jobRun struct {
+	Generation uint64    `json:"generation"`
+	At         time.Time `json:"at"`
+}
+
+var (
+	schedulerJob  = NewStateKey[*jobState]("job")
+	schedulerJobs = NewStateSet[string]("jobs")
+)
+
+// next returns the first run of the job strictly after t.
+func (j Job) next(t, anchor time.Time) (time.Time, error) {
+	switch {
+	case j.Cron != "" && j.Every != "":
+		return time.Time{}, fmt.Errorf("only one of cron and every can be set")
+	case j.Cron != "":
+		schedule, err := cron.Parse(j.Cron)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc := time.UTC
+		if j.TimeZone != "" {
+			if loc, err = time.LoadLocation(j.TimeZone); err != nil {
+				return time.Time{}, err
+			}
+		}
+		next := schedule.Next(t.In(loc))
+		if next.IsZero() {
+			return time.Time{}, fmt.Errorf("cron expression %q never fires", j.Cron)
+		}
+		return next.UTC(), nil
+	case j.Every != "":
+		every, err := time.ParseDuration(j.Every)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if every < time.Second {
+			return time.Time{}, fmt.Errorf("interval must be at least 1s")
+		}
+		if t.Before(anchor) {
+			return anchor, nil
+		}
+		return anchor.Add((t.Sub(anchor)/every + 1) * every), nil
+	default:
+		return time.Time{}, fmt.Errorf("one of cron and every must be set")
+	}
+}
+
+func (j Job) validate() error {
+	if j.Target.Service == "" || j.Target.Handler == "" {
+		return fmt.Errorf("target service and handler must be set")
+	}
+	switch j.MissedRuns {
+	case "", MissedRunsSkip, MissedRunsCatchUp:
+	default:
+		return fmt.Errorf("unknown missed run policy %q", j.MissedRuns)
+	}
+	if len(j.Input) > 0 && !json.Valid(j.Input) {
+		return fmt.Errorf("input is not valid JSON")
+	}
+	return nil
+}
+
+// ScheduleJob creates the job with the given ID, or updates it, replacing its schedule. An
+// updated job keeps its run count, and stays paused if it was.
+//
+// Jobs are run by the virtual object returned by [NewSchedulerService], which must be bound
+// to a server of the same Restate deployment.
+func ScheduleJob(ctx Context, id string, job Job) TerminalError {
+	_, err := Object[Void](ctx, SchedulerServiceName, id, "Schedule").Request(job)
+	return err
+}
+
+// PauseJob stops running the job with the given ID until [ResumeJob] is called.
+func PauseJob(ctx Context, id string) TerminalError {
+	_, err := Object[Void](ctx, SchedulerServiceName, id, "Pause").Request(Void{})
+	return err
+}
+
+// ResumeJob resumes the job with the given ID, applying its [MissedRunPolicy] to the runs
+// missed while paused.
+func ResumeJob(ctx Context, id string) TerminalError {
+	_, err := Object[Void](ctx, SchedulerServiceName, id, "Resume").Request(Void{})
+	return err
+}
+
+// DeleteJob deletes the job with the given ID, if any.
+func DeleteJob(ctx Context, id string) TerminalError {
+	_, err := Object[Void](ctx, SchedulerServiceName, id, "Delete").Request(Void{})
+	return err
+}
+
+// GetJob returns the status of the job with the given ID, failing with a 404 terminal error
+// if there is no such job.
+func GetJob(ctx Context, id string) (JobStatus, TerminalError) {
+	return Object[JobStatus](ctx, SchedulerServiceName, id, "Get").Request(Void{})
+}
+
+// ListJobs returns the IDs of the scheduled jobs.
+func ListJobs(ctx Context) ([]string, TerminalError) {
+	return Object[[]string](ctx, SchedulerServiceName, schedulerIndexKey, "List").Request(Void{})
+}
+
+// NewSchedulerService returns the virtual object running the jobs scheduled with
+// [ScheduleJob], named [SchedulerServiceName]. Bind it once to a server of the Restate
+// deployment:
+//
+//	server.NewRestate().Bind(restate.NewSchedulerService())
+//
+// Each run invokes the target of the job with a one-way call carrying the [JobIDHeader] and
+// [JobScheduledAtHeader] headers. Time is recorded in the journal, so the next run times are
+// deterministic on replay.
+func NewSchedulerService(opts ...options.ServiceDefinitionOption) ServiceDefinition {
+	return NewObject(SchedulerServiceName, append([]options.ServiceDefinitionOption{
+		WithDocumentation("Durable recurring jobs, keyed by job ID."),
+		WithStateKeys(schedulerJob),
+	}, opts...)...).
+		Handler("Schedule", NewObjectHandler(scheduleJob)).
+		Handler("Pause", NewObjectHandler(pauseJob)).
+		Handler("Resume", NewObjectHandler(resumeJob)).
+		Handler("Delete", NewObjectHandler(deleteJob)).
+		Handler("Run", NewObjectHandler(runJob)).
+		Handler("Get", NewObjectSharedHandler(getJob)).
+		Handler("List", NewObjectSharedHandler(listJobs)).
+		Handler("Index", NewObjectHandler(indexJob))
+}
+
+func loadJob(ctx ObjectSharedContext) (*jobState, TerminalError) {
+	if Key(ctx) == schedulerIndexKey {
+		return nil, ToTerminalError(fmt.Errorf("job ID must not be empty"), WithErrorCode(http.StatusBadRequest))
+	}
+	state, err := schedulerJob.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ToTerminalError(fmt.Errorf("job %s not found", Key(ctx)), WithErrorCode(http.StatusNotFound))
+	}
+	return state, nil
+}
+
+// scheduleNext schedules the next run of the job at state.NextRunAt, superseding any pending
+// run.
+func scheduleNext(ctx ObjectContext, state *jobState, now time.Time) {
+	state.Generation++
+	schedulerJob.Set(ctx, state)
+	if !state.Paused {
+		ObjectSend(ctx, SchedulerServiceName, Key(ctx), "Run").
+			Send(jobRun{Generation: state.Generation, At: state.NextRunAt}, WithDelay(max(0, state.NextRunAt.Sub(now))))
+	}
+}
+
+func scheduleJob(ctx ObjectContext, job Job) (Void, error) {
+	if Key(ctx) == schedulerIndexKey {
+		return Void{}, ToTerminalError(fmt.Errorf("job ID must not be empty"), WithErrorCode(http.StatusBadRequest))
+	}
+	if job.MissedRuns == "" {
+		job.MissedRuns = MissedRunsSkip
+	}
+	now, err := now(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	next, nextErr := job.next(now, now)
+	if nextErr == nil {
+		nextErr = job.validate()
+	}
+	if nextErr != nil {
+		return Void{}, ToTerminalError(fmt.Errorf("invalid job %s: %w", Key(ctx), nextErr), WithErrorCode(http.StatusBadRequest))
+	}
+
+	state, err := schedulerJob.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if state == nil {
+		state = &jobState{}
+		ObjectSend(ctx, SchedulerServiceName, schedulerIndexKey, "Index").Send(jobIndexUpdate{ID: Key(ctx), Add: true})
+	}
+	state.Job = job
+	state.Anchor = now
+	state.NextRunAt = next
+	scheduleNext(ctx, state, now)
+	return Void{}, nil
+}
+
+func pauseJob(ctx ObjectContext, _ Void) (Void, error) {
+	state, err := loadJob(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if state.Paused {
+		return Void{}, nil
+	}
+	// NextRunAt is kept, as the first missed run
+	state.Paused = true
+	state.Generation++
+	schedulerJob.Set(ctx, state)
+	return Void{}, nil
+}
+
+func resumeJob(ctx ObjectContext, _ Void) (Void, error) {
+	state, err := loadJob(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if !state.Paused {
+		return Void{}, nil
+	}
+	now, err := now(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	// With MissedRunsCatchUp, the run at NextRunAt catches up from there
+	if state.Job.MissedRuns != MissedRunsCatchUp && state.NextRunAt.Before(now) {
+		next, err := state.Job.next(now, state.Anchor)
+		if err != nil {
+			return Void{}, ToTerminalError(err)
+		}
+		state.NextRunAt = next
+	}
+	state.Paused = false
+	scheduleNext(ctx, state, now)
+	return Void{}, nil
+}
+
+func deleteJob(ctx ObjectContext, _ Void) (Void, error) {
+	state, err := schedulerJob.Get(ctx)
+	if err != nil || state == nil {
+		return Void{}, err
+	}
+	// The pending run finds no job and is ignored
+	schedulerJob.Clear(ctx)
+	ObjectSend(ctx, SchedulerServiceName, schedulerIndexKey, "Index").Send(jobIndexUpdate{ID: Key(ctx)})
+	return Void{}, nil
+}
+
+func runJob(ctx ObjectContext, run jobRun) (Void, error) {
+	state, err := schedulerJob.Get(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if state == nil || state.Paused || state.Generation != run.Generation {
+		// The job was deleted, paused or rescheduled since this run was scheduled
+		return Void{}, nil
+	}
+	now, err := now(ctx)
+	if err != nil {
+		return Void{}, err
+	}
+	if now.Before(run.At) {
+		// Don't run the same scheduled time twice if the clocks disagree
+		now = run.At
+	}
+
+	runs := []time.Time{run.At}
+	for state.Job.MissedRuns == MissedRunsCatchUp && len(runs) < maxCatchUpRuns {
+		next, err := state.Job.next(runs[len(runs)-1], state.Anchor)
+		if err != nil {
+			return Void{}, ToTerminalError(err)
+		}
+		if next.After(now) {
+			break
+		}
+		runs = append(runs, next)
+	}
+	next, nextErr := state.Job.next(now, state.Anchor)
+	if nextErr != nil {
+		return Void{}, ToTerminalError(nextErr)
+	}
+	state.NextRunAt = next
+
+	target := state.Job.Target
+	for _, at := range runs {
+		sendRaw(ctx, target.Service, target.Key, target.Handler, state.Job.Input, WithHeaders(map[string]string{
+			JobIDHeader:          Key(ctx),
+			JobScheduledAtHeader: at.Format(time.RFC3339),
+		}))
+		state.LastRunAt = at
+		state.Runs++
+	}
+	scheduleNext(ctx, state, now)
+	return Void{}, nil
+}
+
+func getJob(ctx ObjectSharedContext, _ Void) (JobStatus, error) {
+	state, err := loadJob(ctx)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	return state.JobStatus, nil
+}
+
+type jobIndexUpdate struct {
+	ID  string `json:"id"`
+	Add bool   `json:"add,omitempty"`
+}
+
+func indexJob(ctx ObjectContext, update jobIndexUpdate) (Void, error) {
+	if Key(ctx) != schedulerIndexKey {
+		return Void{}, ToTerminalError(fmt.Errorf("jobs are only indexed by the scheduler"), WithErrorCode(http.StatusBadRequest))
+	}
+	var err error
+	if update.Add {
+		_, err = schedulerJobs.Add(ctx, update.ID)
+	} else {
+		_, err = schedulerJobs.Delete(ctx, update.ID)
+	}
+	return Void{}, err
+}
+
+func listJobs(ctx ObjectSharedContext, _ Void) ([]string, error) {
+	return schedulerJobs.Members(ctx)
+}
+
+// sendRaw sends an already encoded input to a handler, of a virtual object or workflow if key
+// is set, or of a service otherwise.
+func sendRaw(ctx Context, service, key, handler string, input []byte, opts ...options.SendOption) {
+	if key != "" {
+		ObjectSend(ctx, service, key, handler, WithBinary).Send(input, opts...)
+	} else {
+		ServiceSend(ctx, service, handler, WithBinary).Send(input, opts...)
+	}
+}
@@ -71,11 +71,14 @@ func init() {
 type Restate struct {
 	logHandler     slog.Handler
 	dropReplayLogs bool
+	dropPayloads   bool
 	systemLog      *slog.Logger
 	definitions    map[string]restate.ServiceDefinition
 	keyIDs         []string
 	keySet         identity.KeySetV1
 	protocolMode   internal.ProtocolMode
+
+	errorClassifier func(error) error
 }
 
 // NewRestate creates a new instance of Restate server
@@ -96,11 +99,28 @@ func NewRestate() *Restate {
 // status in a slog.Handler using [github.com/restatedev/sdk-go/logging.LogContextFrom]
 func (r *Restate) WithLogger(h slog.Handler, dropReplayLogs bool) *Restate {
 	r.dropReplayLogs = dropReplayLogs
+	if r.dropPayloads {
+		h = log.WithoutPayloads(h)
+	}
 	r.systemLog = slog.New(log.NewRestateContextHandler(h))
 	r.logHandler = h
 	return r
 }
 
+// WithoutPayloadLogging makes sure that payloads are never logged: the panic values of handlers and
+// the messages of the errors they fail with, which may contain their input, are replaced by
+// [github.com/restatedev/sdk-go/logging.RedactedPlaceholder] in the logs of the SDK, and so are
+// the attributes holding bytes, such as json.RawMessage, in the logs of both the SDK and
+// Context.Log().
+func (r *Restate) WithoutPayloadLogging() *Restate {
+	if !r.dropPayloads {
+		r.dropPayloads = true
+		r.logHandler = log.WithoutPayloads(r.logHandler)
+		r.systemLog = slog.New(log.NewRestateContextHandler(r.logHandler))
+	}
+	return r
+}
+
 // WithIdentityV1 attaches v1 request identity public keys to this server. All incoming requests will be validated
 // against one of these keys.
 func (r *Restate) WithIdentityV1(keys ...string) *Restate {
@@ -108,6 +128,15 @@ func (r *Restate) WithIdentityV1(keys ...string) *Restate {
 	return r
 }
 
+// WithErrorClassifier maps the errors returned by handlers and Run functions to terminal or
+// retryable failures with a code. It applies to the errors left unclassified by the classifier of
+// their service, set with [restate.WithErrorClassifier]. Errors already terminal or retryable
+// are not classified.
+func (r *Restate) WithErrorClassifier(classify func(error) restate.Classification) *Restate {
+	r.errorClassifier = restateerrors.Classifier(classify)
+	return r
+}
+
 // Bidirectional is used to change the protocol mode advertised to Restate on discovery
 // In bidirectional mode, Restate will keep the request body open even after we have started to respond,
 // allowing for more work to be done without suspending.
@@ -593,8 +622,14 @@ func (r *Restate) handleInvokeRequest(service, method string, writer http.Respon
 
 	restatecontext.BufPool.Put(buf)
 
+	var serviceClassifier func(error) error
+	if definition.GetOptions() != nil {
+		serviceClassifier = definition.GetOptions().ErrorClassifier
+	}
+	errorClassifier := restateerrors.ChainClassifiers(serviceClassifier, r.errorClassifier)
+
 	// Run the handler
-	if err := restatecontext.ExecuteInvocation(ctx, logger, stateMachine, stream, handler, r.dropReplayLogs, logHandler, request.Header); err != nil {
+	if err := restatecontext.ExecuteInvocation(ctx, logger, stateMachine, stream, service, method, handler, r.dropReplayLogs, logHandler, request.Header, errorClassifier); err != nil {
 		r.systemLog.LogAttrs(ctx, slog.LevelError, "Failed to handle invocation", log.Error(err))
 	}
 }
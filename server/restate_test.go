@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/internal/restatecontext"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Types and fields of the service protocol messages exchanged in the tests.
+const (
+	startMessageType          uint16 = 0x0000
+	inputCommandType          uint16 = 0x0400
+	outputCommandType         uint16 = 0x0401
+	proposeRunCompletionType  uint16 = 0x0005
+	outputCommandFailureField        = 15
+	proposalFailureField             = 15
+	failureCodeField                 = 1
+)
+
+func encodeMessage(typ uint16, body []byte) []byte {
+	frame := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint16(frame[0:2], typ)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(body)))
+	return append(frame, body...)
+}
+
+// invocationMessages returns the messages starting an invocation without input.
+func invocationMessages() []byte {
+	var start []byte
+	start = protowire.AppendTag(start, 1, protowire.BytesType)
+	start = protowire.AppendBytes(start, []byte("invocation-id"))
+	start = protowire.AppendTag(start, 2, protowire.BytesType)
+	start = protowire.AppendString(start, "inv_test")
+	start = protowire.AppendTag(start, 3, protowire.VarintType)
+	start = protowire.AppendVarint(start, 1)
+
+	var value []byte
+	value = protowire.AppendTag(value, 1, protowire.BytesType)
+	value = protowire.AppendBytes(value, nil)
+	var input []byte
+	input = protowire.AppendTag(input, 14, protowire.BytesType)
+	input = protowire.AppendBytes(input, value)
+
+	return append(encodeMessage(startMessageType, start), encodeMessage(inputCommandType, input)...)
+}
+
+// failureCode returns the code of the failure in the field number of the first message of type
+// typ written by the invocation.
+func failureCode(t *testing.T, output []byte, typ uint16, number protowire.Number) uint64 {
+	for len(output) >= 8 {
+		length := int(binary.BigEndian.Uint32(output[4:8]))
+		body := output[8 : 8+length]
+		if binary.BigEndian.Uint16(output[0:2]) == typ {
+			failure := fieldValue(body, number)
+			require.NotNil(t, failure, "message of type %#x is not a failure", typ)
+			code, n := protowire.ConsumeVarint(fieldValue(failure, failureCodeField))
+			require.Positive(t, n)
+			return code
+		}
+		output = output[8+length:]
+	}
+	require.FailNowf(t, "missing message", "no message of type %#x", typ)
+	return 0
+}
+
+// fieldValue returns the raw value of the field number of the protobuf message body.
+func fieldValue(body []byte, number protowire.Number) []byte {
+	for len(body) > 0 {
+		num, typ, n := protowire.ConsumeTag(body)
+		if n < 0 {
+			return nil
+		}
+		body = body[n:]
+		n = protowire.ConsumeFieldValue(num, typ, body)
+		if n < 0 {
+			return nil
+		}
+		if num == number {
+			if typ == protowire.BytesType {
+				value, _ := protowire.ConsumeBytes(body)
+				return value
+			}
+			return body[:n]
+		}
+		body = body[n:]
+	}
+	return nil
+}
+
+func invoke(t *testing.T, server *Restate, service, handler string) []byte {
+	h, err := server.Handler()
+	require.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/invoke/"+service+"/"+handler, bytes.NewReader(invocationMessages()))
+	request.Header.Set("content-type", "application/vnd.restate.invocation.v5")
+	recorder := httptest.NewRecorder()
+	h(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	return recorder.Body.Bytes()
+}
+
+func TestErrorClassifier(t *testing.T) {
+	errNotFound := errors.New("not found")
+	errTimeout := errors.New("timeout")
+	failing := func(err error) restatecontext.Handler {
+		return restate.NewServiceHandler(func(ctx restate.Context, _ restate.Void) (restate.Void, error) {
+			return restate.Void{}, err
+		})
+	}
+	failingRun := func(err error) restatecontext.Handler {
+		return restate.NewServiceHandler(func(ctx restate.Context, _ restate.Void) (restate.Void, error) {
+			return restate.Void{}, restate.RunVoid(ctx, func(restate.RunContext) error {
+				return err
+			})
+		})
+	}
+
+	server := NewRestate().
+		WithLogger(slog.DiscardHandler, false).
+		Bidirectional(false).
+		WithErrorClassifier(func(err error) restate.Classification {
+			return restate.TerminalClassification(502)
+		}).
+		Bind(restate.NewService("Orders", restate.WithErrorClassifier(func(err error) restate.Classification {
+			if errors.Is(err, errNotFound) {
+				return restate.TerminalClassification(404)
+			}
+			return restate.Classification{}
+		})).
+			Handler("Get", failing(errNotFound)).
+			Handler("Charge", failing(errTimeout)).
+			Handler("Reserve", failingRun(errNotFound))).
+		Bind(restate.NewService("Payments").
+			Handler("Charge", failingRun(errTimeout)))
+
+	// The classifier of the service takes precedence over the one of the server
+	require.Equal(t, uint64(404), failureCode(t, invoke(t, server, "Orders", "Get"), outputCommandType, outputCommandFailureField))
+	require.Equal(t, uint64(502), failureCode(t, invoke(t, server, "Orders", "Charge"), outputCommandType, outputCommandFailureField))
+
+	// Run functions are classified too
+	require.Equal(t, uint64(404), failureCode(t, invoke(t, server, "Orders", "Reserve"), proposeRunCompletionType, proposalFailureField))
+	require.Equal(t, uint64(502), failureCode(t, invoke(t, server, "Payments", "Charge"), proposeRunCompletionType, proposalFailureField))
+}
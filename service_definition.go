@@ -48,6 +48,39 @@ func (r *serviceDefinition) Type() internal.ServiceType {
 	return r.typ
 }
 
+// typedHandler is implemented by the handlers knowing their input and output types, to look up
+// the codecs registered for them with [encoding.Register].
+type typedHandler interface {
+	registeredCodecs() (input encoding.Codec, output encoding.Codec)
+}
+
+// setDefaultCodecs sets the codecs of handler that weren't set explicitly to the default codec
+// of the service if any, or else to the codecs registered for its input and output types, or
+// else to JSON.
+func (r *serviceDefinition) setDefaultCodecs(handler restatecontext.Handler) {
+	var input, output encoding.Codec
+	if typed, ok := handler.(typedHandler); ok {
+		input, output = typed.registeredCodecs()
+	}
+	o := handler.GetOptions()
+	if o.InputCodec == nil {
+		o.InputCodec = defaultCodec(r.options.DefaultCodec, input)
+	}
+	if o.OutputCodec == nil {
+		o.OutputCodec = defaultCodec(r.options.DefaultCodec, output)
+	}
+}
+
+func defaultCodec(serviceCodec encoding.Codec, registeredCodec encoding.Codec) encoding.Codec {
+	if serviceCodec != nil {
+		return serviceCodec
+	}
+	if registeredCodec != nil {
+		return registeredCodec
+	}
+	return encoding.JSONCodec
+}
+
 func (r *serviceDefinition) ConfigureHandler(name string, opts ...options.HandlerOption) ServiceDefinition {
 	handler := r.handlers[name]
 	if handler == nil {
@@ -70,9 +103,6 @@ func NewService(name string, opts ...options.ServiceDefinitionOption) *service {
 	for _, opt := range opts {
 		opt.BeforeServiceDefinition(&o)
 	}
-	if o.DefaultCodec == nil {
-		o.DefaultCodec = encoding.JSONCodec
-	}
 	if o.WorkflowRetention != nil {
 		panic("Workflow retention can be set only for workflows")
 	}
@@ -88,11 +118,9 @@ func NewService(name string, opts ...options.ServiceDefinitionOption) *service {
 
 // Handler registers a new Service handler by name
 func (r *service) Handler(name string, handler restatecontext.Handler) *service {
-	if handler.GetOptions().InputCodec == nil {
-		handler.GetOptions().InputCodec = r.options.DefaultCodec
-	}
-	if handler.GetOptions().OutputCodec == nil {
-		handler.GetOptions().OutputCodec = r.options.DefaultCodec
+	r.setDefaultCodecs(handler)
+	if handler.GetOptions().Validator == nil {
+		handler.GetOptions().Validator = r.options.DefaultValidator
 	}
 	r.handlers[name] = handler
 	return r
@@ -108,9 +136,6 @@ func NewObject(name string, opts ...options.ServiceDefinitionOption) *object {
 	for _, opt := range opts {
 		opt.BeforeServiceDefinition(&o)
 	}
-	if o.DefaultCodec == nil {
-		o.DefaultCodec = encoding.JSONCodec
-	}
 	if o.WorkflowRetention != nil {
 		panic("Workflow retention can be set only for workflows")
 	}
@@ -126,11 +151,12 @@ func NewObject(name string, opts ...options.ServiceDefinitionOption) *object {
 
 // Handler registers a new Virtual Object handler by name
 func (r *object) Handler(name string, handler restatecontext.Handler) *object {
-	if handler.GetOptions().InputCodec == nil {
-		handler.GetOptions().InputCodec = r.options.DefaultCodec
+	r.setDefaultCodecs(handler)
+	if handler.GetOptions().Validator == nil {
+		handler.GetOptions().Validator = r.options.DefaultValidator
 	}
-	if handler.GetOptions().OutputCodec == nil {
-		handler.GetOptions().OutputCodec = r.options.DefaultCodec
+	if r.options.StateTTL {
+		handler.GetOptions().StateTTL = true
 	}
 	r.handlers[name] = handler
 	return r
@@ -146,9 +172,6 @@ func NewWorkflow(name string, opts ...options.ServiceDefinitionOption) *workflow
 	for _, opt := range opts {
 		opt.BeforeServiceDefinition(&o)
 	}
-	if o.DefaultCodec == nil {
-		o.DefaultCodec = encoding.JSONCodec
-	}
 	return &workflow{
 		serviceDefinition: serviceDefinition{
 			name:     name,
@@ -161,11 +184,9 @@ func NewWorkflow(name string, opts ...options.ServiceDefinitionOption) *workflow
 
 // Handler registers a new Workflow handler by name
 func (r *workflow) Handler(name string, handler restatecontext.Handler) *workflow {
-	if handler.GetOptions().InputCodec == nil {
-		handler.GetOptions().InputCodec = r.options.DefaultCodec
-	}
-	if handler.GetOptions().OutputCodec == nil {
-		handler.GetOptions().OutputCodec = r.options.DefaultCodec
+	r.setDefaultCodecs(handler)
+	if handler.GetOptions().Validator == nil {
+		handler.GetOptions().Validator = r.options.DefaultValidator
 	}
 	r.handlers[name] = handler
 	return r
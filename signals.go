@@ -14,7 +14,7 @@ type ResolveSignalOption = options.ResolveSignalOption
 
 // Signal returns a future for a signal by name.
 func Signal[T any](ctx Context, name string, options ...options.SignalOption) SignalFuture[T] {
-	return genericfutures.SignalFuture[T]{SignalFuture: ctx.inner().Signal(name, options...)}
+	return genericfutures.SignalFuture[T]{SignalFuture: ctx.inner().Signal(name, withRegisteredCodec[T](options)...)}
 }
 
 // SignalFuture is a promise to a future signal value or error.
@@ -29,7 +29,7 @@ type SignalFuture[T any] interface {
 
 // ResolveSignal resolves a signal on an invocation with a particular value.
 func ResolveSignal[T any](ctx Context, invocationID string, name string, value T, options ...options.ResolveSignalOption) {
-	ctx.inner().ResolveSignal(invocationID, name, value, options...)
+	ctx.inner().ResolveSignal(invocationID, name, value, withRegisteredCodec[T](options)...)
 }
 
 // RejectSignal rejects a signal on an invocation with a particular error.
@@ -1,6 +1,11 @@
 package restate
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/restatedev/sdk-go/encoding"
 	"github.com/restatedev/sdk-go/internal/options"
 )
 
@@ -14,19 +19,54 @@ type SetOption = options.SetOption
 // To check explicitly for this case pass a pointer eg *string as T.
 // If the invocation was cancelled while obtaining the state (only possible if eager state is disabled),
 // a cancellation error is returned.
+// In virtual objects registered with [EnableStateTTL], entries set with [SetWithTTL] are
+// treated as absent once expired.
 func Get[T any](ctx ObjectSharedContext, key string, options ...options.GetOption) (output T, err TerminalError) {
-	_, err = ctx.inner().Get(key, &output, options...)
+	_, err = getState(ctx, key, &output, withRegisteredCodec[T](options)...)
 	return output, err
 }
 
+// GetAndMigrate gets the value for a key like [Get]. When the codec is an
+// [encoding.MigratingCodec], such as [encoding.Versioned], and the stored value was written
+// with an older schema version, the upgraded value is also written back, so that later reads
+// don't need to migrate it again.
+func GetAndMigrate[T any](ctx ObjectContext, key string, opts ...options.GetOption) (output T, err TerminalError) {
+	opts = withRegisteredCodec[T](opts)
+	o := options.GetOptions{}
+	for _, opt := range opts {
+		opt.BeforeGet(&o)
+	}
+	codec, ok := o.Codec.(encoding.MigratingCodec)
+	if !ok {
+		return Get[T](ctx, key, opts...)
+	}
+
+	data, expiresAt, found, err := getRawState(ctx, key)
+	if err != nil || !found {
+		return output, err
+	}
+	if err := encoding.Unmarshal(codec, data, &output); err != nil {
+		panic(fmt.Errorf("failed to unmarshal Get state into output: %w", err))
+	}
+	if codec.NeedsMigration(data) {
+		if expiresAt.IsZero() {
+			ctx.inner().Set(key, output, WithCodec(codec))
+		} else {
+			setStateWithTTL(ctx, key, output, codec, expiresAt)
+		}
+	}
+	return output, nil
+}
+
 // Keys retrieves all the state keys set inside a virtual object instance.
 func Keys(ctx ObjectSharedContext) ([]string, TerminalError) {
 	return ctx.inner().Keys()
 }
 
-// Set sets a value against a key, using the provided codec (defaults to JSON)
+// Set sets a value against a key, using the provided codec (defaults to the codec registered
+// for T with [encoding.Register], or JSON)
 func Set[T any](ctx ObjectContext, key string, value T, options ...options.SetOption) {
-	ctx.inner().Set(key, value, options...)
+	ctx.inner().Set(key, value, withRegisteredCodec[T](options)...)
 }
 
 // Clear deletes a key
@@ -38,3 +78,151 @@ func Clear(ctx ObjectContext, key string) {
 func ClearAll(ctx ObjectContext) {
 	ctx.inner().ClearAll()
 }
+
+// StateKeyOption is an option for [NewStateKey] and [NewStateKeyWithDefault].
+type StateKeyOption = options.StateKeyOption
+
+// StateKey is a typed declaration of a virtual object or workflow state key. Declaring keys
+// once, and using the methods on the declaration rather than [Get] / [Set] with bare strings,
+// ensures every handler agrees on the key name, the value type and the codec.
+//
+// Declared keys can be advertised in the discovery manifest by passing them to
+// [WithStateKeys] when defining the service.
+type StateKey[T any] struct {
+	name         string
+	codec        encoding.Codec
+	defaultValue T
+	hasDefault   bool
+}
+
+// NewStateKey declares a state key holding values of type T. When the key is not set,
+// [StateKey.Get] returns the zero value of T. The codec defaults to the one registered for T
+// with [encoding.Register], or JSON, and can be changed with [WithCodec]. The registered codec
+// is looked up when the key is used, so keys can be declared in package variables before the
+// codec is registered in an init function.
+func NewStateKey[T any](name string, opts ...options.StateKeyOption) StateKey[T] {
+	o := options.StateKeyOptions{}
+	for _, opt := range opts {
+		opt.BeforeStateKey(&o)
+	}
+	return StateKey[T]{name: name, codec: o.Codec}
+}
+
+// NewStateKeyWithDefault declares a state key holding values of type T, for which
+// [StateKey.Get] returns defaultValue when the key is not set.
+func NewStateKeyWithDefault[T any](name string, defaultValue T, opts ...options.StateKeyOption) StateKey[T] {
+	key := NewStateKey[T](name, opts...)
+	key.defaultValue = defaultValue
+	key.hasDefault = true
+	return key
+}
+
+// keyCodec returns the codec of the key, set with [WithCodec] or else the one registered
+// for T, defaulting to JSON.
+func (k StateKey[T]) keyCodec() encoding.Codec {
+	if k.codec != nil {
+		return k.codec
+	}
+	if codec := encoding.RegisteredCodec(reflect.TypeFor[T]()); codec != nil {
+		return codec
+	}
+	return encoding.JSONCodec
+}
+
+// Name returns the name of the state key.
+func (k StateKey[T]) Name() string {
+	return k.name
+}
+
+// Get gets the value of the key, returning the default value (or the zero value of T if
+// none was declared) when the key is not set, or has expired like with [Get].
+func (k StateKey[T]) Get(ctx ObjectSharedContext) (output T, err TerminalError) {
+	found, err := getState(ctx, k.name, &output, WithCodec(k.keyCodec()))
+	if err != nil {
+		return output, err
+	}
+	if !found && k.hasDefault {
+		return k.defaultValue, nil
+	}
+	return output, nil
+}
+
+// Set sets the value of the key.
+func (k StateKey[T]) Set(ctx ObjectContext, value T) {
+	ctx.inner().Set(k.name, value, WithCodec(k.keyCodec()))
+}
+
+// Clear deletes the key.
+func (k StateKey[T]) Clear(ctx ObjectContext) {
+	ctx.inner().Clear(k.name)
+}
+
+// Update reads the current value of the key (or its default), applies fn and stores the
+// result, which is also returned.
+func (k StateKey[T]) Update(ctx ObjectContext, fn func(T) T) (T, TerminalError) {
+	value, err := k.Get(ctx)
+	if err != nil {
+		return value, err
+	}
+	value = fn(value)
+	k.Set(ctx, value)
+	return value, nil
+}
+
+func (k StateKey[T]) stateKeyMetadata() (string, string) {
+	var zero T
+	payload := encoding.OutputPayloadFor(k.keyCodec(), zero)
+	description := stateKeyDescription{
+		ContentType: payload.ContentType,
+		JsonSchema:  payload.JsonSchema,
+	}
+	if k.hasDefault {
+		if bytes, err := encoding.Marshal(k.keyCodec(), k.defaultValue); err == nil && json.Valid(bytes) {
+			description.Default = bytes
+		}
+	}
+	bytes, err := json.Marshal(description)
+	if err != nil {
+		panic(fmt.Errorf("failed to describe state key %s: %w", k.name, err))
+	}
+	return stateKeyMetadataPrefix + k.name, string(bytes)
+}
+
+// StateKeyDescriptor is implemented by every [StateKey], regardless of its value type, so
+// that keys of different types can be passed together to [WithStateKeys].
+type StateKeyDescriptor interface {
+	Name() string
+	stateKeyMetadata() (string, string)
+}
+
+// stateKeyMetadataPrefix prefixes the metadata entries describing declared state keys.
+const stateKeyMetadataPrefix = "restate.state."
+
+type stateKeyDescription struct {
+	ContentType *string         `json:"contentType,omitempty"`
+	JsonSchema  any             `json:"jsonSchema,omitempty"`
+	Default     json.RawMessage `json:"default,omitempty"`
+}
+
+type withStateKeys struct {
+	keys []StateKeyDescriptor
+}
+
+var _ options.ServiceDefinitionOption = withStateKeys{}
+
+func (w withStateKeys) BeforeServiceDefinition(opts *options.ServiceDefinitionOptions) {
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string, len(w.keys))
+	}
+	for _, key := range w.keys {
+		k, v := key.stateKeyMetadata()
+		opts.Metadata[k] = v
+	}
+}
+
+// WithStateKeys advertises the given state keys in the discovery manifest, as service
+// metadata entries named "restate.state.<key>" holding the content type and JSON schema of
+// the value, so that tools such as the Restate UI can show typed state.
+func WithStateKeys(keys ...StateKeyDescriptor) withStateKeys {
+	return withStateKeys{keys}
+}
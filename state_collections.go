@@ -0,0 +1,507 @@
+package restate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal/options"
+)
+
+// Durable collections spread their entries across several state keys, so that updating a
+// single entry doesn't rewrite the whole collection. A collection named "name" owns the key
+// "name", holding a small JSON index, and every key prefixed with "name/".
+
+// StateCollectionOption is an option for [NewStateList], [NewStateMap] and [NewStateSet].
+type StateCollectionOption = options.StateCollectionOption
+
+// DefaultStateListChunkSize is the number of entries stored per state key by a [StateList],
+// unless overridden with [WithChunkSize].
+const DefaultStateListChunkSize = 64
+
+type withChunkSize struct {
+	chunkSize int
+}
+
+var _ options.StateCollectionOption = withChunkSize{}
+
+func (w withChunkSize) BeforeStateCollection(opts *options.StateCollectionOptions) {
+	opts.ChunkSize = w.chunkSize
+}
+
+// WithChunkSize sets how many entries a [StateList] stores per state key. Larger chunks mean
+// fewer state keys but more bytes rewritten per update.
+func WithChunkSize(chunkSize int) withChunkSize {
+	return withChunkSize{chunkSize}
+}
+
+func newStateCollectionOptions(opts []options.StateCollectionOption) options.StateCollectionOptions {
+	o := options.StateCollectionOptions{}
+	for _, opt := range opts {
+		opt.BeforeStateCollection(&o)
+	}
+	if o.Codec == nil {
+		o.Codec = encoding.JSONCodec
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultStateListChunkSize
+	}
+	return o
+}
+
+// stateCollectionKeys returns the sorted state keys belonging to the collection name,
+// excluding its index key.
+func stateCollectionKeys(ctx ObjectSharedContext, name string) ([]string, TerminalError) {
+	keys, err := Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := name + "/"
+	owned := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			owned = append(owned, key)
+		}
+	}
+	slices.Sort(owned)
+	return owned, nil
+}
+
+func clearStateCollection(ctx ObjectContext, name string) TerminalError {
+	keys, err := stateCollectionKeys(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		Clear(ctx, key)
+	}
+	Clear(ctx, name)
+	return nil
+}
+
+func indexOutOfRange(name string, index, length int) TerminalError {
+	return ToTerminalError(fmt.Errorf("index %d out of range for state list %s of length %d", index, name, length), WithErrorCode(http.StatusNotFound))
+}
+
+// StateList is a durable list stored in virtual object state. Entries are grouped in chunks
+// of a fixed size, each stored under its own state key, and a small index records the
+// chunks in order, so appending or deleting an entry only rewrites one chunk and the index.
+type StateList[T any] struct {
+	name      string
+	codec     encoding.Codec
+	chunkSize int
+}
+
+type stateListIndex struct {
+	Chunks []stateListChunk `json:"chunks"`
+	NextID uint64           `json:"nextId"`
+}
+
+type stateListChunk struct {
+	ID  uint64 `json:"id"`
+	Len int    `json:"len"`
+}
+
+func (i stateListIndex) length() int {
+	length := 0
+	for _, chunk := range i.Chunks {
+		length += chunk.Len
+	}
+	return length
+}
+
+// locate returns the position in Chunks of the chunk holding the entry at index, and the
+// offset of the entry within that chunk.
+func (i stateListIndex) locate(index int) (int, int, bool) {
+	if index < 0 {
+		return 0, 0, false
+	}
+	for pos, chunk := range i.Chunks {
+		if index < chunk.Len {
+			return pos, index, true
+		}
+		index -= chunk.Len
+	}
+	return 0, 0, false
+}
+
+// NewStateList declares a durable list named name, holding values of type T. The codec
+// used for entries defaults to JSON and can be changed with [WithCodec].
+func NewStateList[T any](name string, opts ...options.StateCollectionOption) StateList[T] {
+	o := newStateCollectionOptions(opts)
+	return StateList[T]{name: name, codec: o.Codec, chunkSize: o.ChunkSize}
+}
+
+// Name returns the name of the list.
+func (l StateList[T]) Name() string {
+	return l.name
+}
+
+func (l StateList[T]) index(ctx ObjectSharedContext) (stateListIndex, TerminalError) {
+	return Get[stateListIndex](ctx, l.name, WithJSON)
+}
+
+func (l StateList[T]) chunkKey(id uint64) string {
+	return l.name + "/" + strconv.FormatUint(id, 10)
+}
+
+func (l StateList[T]) chunk(ctx ObjectSharedContext, id uint64) ([]T, TerminalError) {
+	return Get[[]T](ctx, l.chunkKey(id), WithCodec(l.codec))
+}
+
+// Len returns the number of entries in the list.
+func (l StateList[T]) Len(ctx ObjectSharedContext) (int, TerminalError) {
+	index, err := l.index(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return index.length(), nil
+}
+
+// Append adds values at the end of the list.
+func (l StateList[T]) Append(ctx ObjectContext, values ...T) TerminalError {
+	if len(values) == 0 {
+		return nil
+	}
+	index, err := l.index(ctx)
+	if err != nil {
+		return err
+	}
+
+	var chunk []T
+	if last := len(index.Chunks) - 1; last >= 0 && index.Chunks[last].Len < l.chunkSize {
+		if chunk, err = l.chunk(ctx, index.Chunks[last].ID); err != nil {
+			return err
+		}
+	}
+	for len(values) > 0 {
+		if len(chunk) == 0 || len(chunk) >= l.chunkSize {
+			chunk = nil
+			index.Chunks = append(index.Chunks, stateListChunk{ID: index.NextID})
+			index.NextID++
+		}
+		n := min(l.chunkSize-len(chunk), len(values))
+		chunk = append(chunk, values[:n]...)
+		values = values[n:]
+
+		last := &index.Chunks[len(index.Chunks)-1]
+		last.Len = len(chunk)
+		Set(ctx, l.chunkKey(last.ID), chunk, WithCodec(l.codec))
+	}
+	Set(ctx, l.name, index, WithJSON)
+	return nil
+}
+
+// Get returns the entry at position i, or a terminal error with code 404 if i is out of range.
+func (l StateList[T]) Get(ctx ObjectSharedContext, i int) (output T, err TerminalError) {
+	index, err := l.index(ctx)
+	if err != nil {
+		return output, err
+	}
+	pos, offset, ok := index.locate(i)
+	if !ok {
+		return output, indexOutOfRange(l.name, i, index.length())
+	}
+	chunk, err := l.chunk(ctx, index.Chunks[pos].ID)
+	if err != nil {
+		return output, err
+	}
+	if offset >= len(chunk) {
+		return output, indexOutOfRange(l.name, i, index.length())
+	}
+	return chunk[offset], nil
+}
+
+// Range returns up to limit entries starting at position offset. A limit <= 0 returns all
+// the entries from offset to the end of the list. Only the chunks covering the requested
+// page are read.
+func (l StateList[T]) Range(ctx ObjectSharedContext, offset, limit int) ([]T, TerminalError) {
+	index, err := l.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = index.length()
+	}
+	pos, skip, ok := index.locate(offset)
+	if !ok {
+		return nil, nil
+	}
+
+	var output []T
+	for ; pos < len(index.Chunks) && len(output) < limit; pos++ {
+		chunk, err := l.chunk(ctx, index.Chunks[pos].ID)
+		if err != nil {
+			return nil, err
+		}
+		if skip < len(chunk) {
+			chunk = chunk[skip:]
+			output = append(output, chunk[:min(len(chunk), limit-len(output))]...)
+		}
+		skip = 0
+	}
+	return output, nil
+}
+
+// Delete removes the entry at position i, shifting the following entries down by one. It
+// returns a terminal error with code 404 if i is out of range.
+func (l StateList[T]) Delete(ctx ObjectContext, i int) TerminalError {
+	index, err := l.index(ctx)
+	if err != nil {
+		return err
+	}
+	pos, offset, ok := index.locate(i)
+	if !ok {
+		return indexOutOfRange(l.name, i, index.length())
+	}
+	chunkInfo := &index.Chunks[pos]
+	chunk, err := l.chunk(ctx, chunkInfo.ID)
+	if err != nil {
+		return err
+	}
+	if offset >= len(chunk) {
+		return indexOutOfRange(l.name, i, index.length())
+	}
+
+	chunk = slices.Delete(chunk, offset, offset+1)
+	if len(chunk) == 0 {
+		Clear(ctx, l.chunkKey(chunkInfo.ID))
+		index.Chunks = slices.Delete(index.Chunks, pos, pos+1)
+	} else {
+		chunkInfo.Len = len(chunk)
+		Set(ctx, l.chunkKey(chunkInfo.ID), chunk, WithCodec(l.codec))
+	}
+
+	if len(index.Chunks) == 0 {
+		Clear(ctx, l.name)
+	} else {
+		Set(ctx, l.name, index, WithJSON)
+	}
+	return nil
+}
+
+// Clear removes every entry of the list.
+func (l StateList[T]) Clear(ctx ObjectContext) TerminalError {
+	return clearStateCollection(ctx, l.name)
+}
+
+// StateMapEntry is a key/value pair returned by [StateMap.Range].
+type StateMapEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// StateMap is a durable map stored in virtual object state. Each entry is stored under its
+// own state key, derived from the JSON encoding of the map key, and the index only tracks the
+// number of entries, so reading or writing one entry never touches the others.
+type StateMap[K comparable, V any] struct {
+	name  string
+	codec encoding.Codec
+}
+
+type stateMapIndex struct {
+	Len int `json:"len"`
+}
+
+// NewStateMap declares a durable map named name. Map keys are encoded as JSON to build the
+// state key of each entry; the codec used for values defaults to JSON and can be changed
+// with [WithCodec].
+func NewStateMap[K comparable, V any](name string, opts ...options.StateCollectionOption) StateMap[K, V] {
+	o := newStateCollectionOptions(opts)
+	return StateMap[K, V]{name: name, codec: o.Codec}
+}
+
+// Name returns the name of the map.
+func (m StateMap[K, V]) Name() string {
+	return m.name
+}
+
+func (m StateMap[K, V]) entryKey(key K) string {
+	bytes, err := json.Marshal(key)
+	if err != nil {
+		panic(fmt.Errorf("failed to encode key of state map %s: %w", m.name, err))
+	}
+	return m.name + "/" + string(bytes)
+}
+
+func (m StateMap[K, V]) decodeEntryKey(entryKey string) K {
+	var key K
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(entryKey, m.name+"/")), &key); err != nil {
+		panic(fmt.Errorf("failed to decode key of state map %s: %w", m.name, err))
+	}
+	return key
+}
+
+func (m StateMap[K, V]) index(ctx ObjectSharedContext) (stateMapIndex, TerminalError) {
+	return Get[stateMapIndex](ctx, m.name, WithJSON)
+}
+
+// Len returns the number of entries in the map.
+func (m StateMap[K, V]) Len(ctx ObjectSharedContext) (int, TerminalError) {
+	index, err := m.index(ctx)
+	return index.Len, err
+}
+
+// Get returns the value stored for key, and whether it was present.
+func (m StateMap[K, V]) Get(ctx ObjectSharedContext, key K) (output V, found bool, err TerminalError) {
+	found, err = ctx.inner().Get(m.entryKey(key), &output, WithCodec(m.codec))
+	return output, found, err
+}
+
+// Contains reports whether key is present in the map.
+func (m StateMap[K, V]) Contains(ctx ObjectSharedContext, key K) (bool, TerminalError) {
+	return ctx.inner().Get(m.entryKey(key), &Void{})
+}
+
+// Put stores value for key, replacing any previous value.
+func (m StateMap[K, V]) Put(ctx ObjectContext, key K, value V) TerminalError {
+	found, err := m.Contains(ctx, key)
+	if err != nil {
+		return err
+	}
+	Set(ctx, m.entryKey(key), value, WithCodec(m.codec))
+	if !found {
+		index, err := m.index(ctx)
+		if err != nil {
+			return err
+		}
+		index.Len++
+		Set(ctx, m.name, index, WithJSON)
+	}
+	return nil
+}
+
+// Delete removes key from the map, reporting whether it was present.
+func (m StateMap[K, V]) Delete(ctx ObjectContext, key K) (bool, TerminalError) {
+	found, err := m.Contains(ctx, key)
+	if err != nil || !found {
+		return false, err
+	}
+	Clear(ctx, m.entryKey(key))
+	index, err := m.index(ctx)
+	if err != nil {
+		return false, err
+	}
+	if index.Len--; index.Len <= 0 {
+		Clear(ctx, m.name)
+	} else {
+		Set(ctx, m.name, index, WithJSON)
+	}
+	return true, nil
+}
+
+// Keys returns the keys of the map, ordered by their JSON encoding.
+func (m StateMap[K, V]) Keys(ctx ObjectSharedContext) ([]K, TerminalError) {
+	entryKeys, err := stateCollectionKeys(ctx, m.name)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]K, 0, len(entryKeys))
+	for _, entryKey := range entryKeys {
+		keys = append(keys, m.decodeEntryKey(entryKey))
+	}
+	return keys, nil
+}
+
+// Range returns up to limit entries starting at position offset, in the order of [StateMap.Keys].
+// A limit <= 0 returns all the entries from offset onwards.
+func (m StateMap[K, V]) Range(ctx ObjectSharedContext, offset, limit int) ([]StateMapEntry[K, V], TerminalError) {
+	entryKeys, err := stateCollectionKeys(ctx, m.name)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset >= len(entryKeys) {
+		return nil, nil
+	}
+	entryKeys = entryKeys[offset:]
+	if limit > 0 && limit < len(entryKeys) {
+		entryKeys = entryKeys[:limit]
+	}
+
+	entries := make([]StateMapEntry[K, V], 0, len(entryKeys))
+	for _, entryKey := range entryKeys {
+		value, err := Get[V](ctx, entryKey, WithCodec(m.codec))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, StateMapEntry[K, V]{Key: m.decodeEntryKey(entryKey), Value: value})
+	}
+	return entries, nil
+}
+
+// Clear removes every entry of the map.
+func (m StateMap[K, V]) Clear(ctx ObjectContext) TerminalError {
+	return clearStateCollection(ctx, m.name)
+}
+
+// StateSet is a durable set stored in virtual object state, with the same layout as a
+// [StateMap] whose values are empty.
+type StateSet[T comparable] struct {
+	entries StateMap[T, struct{}]
+}
+
+// NewStateSet declares a durable set named name. Members are encoded as JSON to build the
+// state key of each member.
+func NewStateSet[T comparable](name string, opts ...options.StateCollectionOption) StateSet[T] {
+	return StateSet[T]{entries: NewStateMap[T, struct{}](name, opts...)}
+}
+
+// Name returns the name of the set.
+func (s StateSet[T]) Name() string {
+	return s.entries.name
+}
+
+// Len returns the number of members of the set.
+func (s StateSet[T]) Len(ctx ObjectSharedContext) (int, TerminalError) {
+	return s.entries.Len(ctx)
+}
+
+// Contains reports whether value is a member of the set.
+func (s StateSet[T]) Contains(ctx ObjectSharedContext, value T) (bool, TerminalError) {
+	return s.entries.Contains(ctx, value)
+}
+
+// Add adds value to the set, reporting whether it was not already a member.
+func (s StateSet[T]) Add(ctx ObjectContext, value T) (bool, TerminalError) {
+	found, err := s.entries.Contains(ctx, value)
+	if err != nil || found {
+		return false, err
+	}
+	return true, s.entries.Put(ctx, value, struct{}{})
+}
+
+// Delete removes value from the set, reporting whether it was a member.
+func (s StateSet[T]) Delete(ctx ObjectContext, value T) (bool, TerminalError) {
+	return s.entries.Delete(ctx, value)
+}
+
+// Members returns the members of the set, ordered by their JSON encoding.
+func (s StateSet[T]) Members(ctx ObjectSharedContext) ([]T, TerminalError) {
+	return s.entries.Keys(ctx)
+}
+
+// Range returns up to limit members starting at position offset, in the order of
+// [StateSet.Members]. A limit <= 0 returns all the members from offset onwards.
+func (s StateSet[T]) Range(ctx ObjectSharedContext, offset, limit int) ([]T, TerminalError) {
+	members, err := s.entries.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset >= len(members) {
+		return nil, nil
+	}
+	members = members[offset:]
+	if limit > 0 && limit < len(members) {
+		members = members[:limit]
+	}
+	return members, nil
+}
+
+// Clear removes every member of the set.
+func (s StateSet[T]) Clear(ctx ObjectContext) TerminalError {
+	return s.entries.Clear(ctx)
+}
@@ -0,0 +1,25 @@
+package restate_test
+
+import (
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateKeysMetadata(t *testing.T) {
+	count := restate.NewStateKeyWithDefault("count", 10)
+	names := restate.NewStateKey[[]string]("names", restate.WithJSON)
+	raw := restate.NewStateKey[[]byte]("raw", restate.WithBinary)
+
+	def := restate.NewObject("Counter",
+		restate.WithStateKeys(count, names, raw),
+		restate.WithMetadata("owner", "team"),
+	)
+
+	metadata := def.GetOptions().Metadata
+	require.Equal(t, "team", metadata["owner"])
+	require.JSONEq(t, `{"contentType":"application/json","jsonSchema":{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"integer"},"default":10}`, metadata["restate.state.count"])
+	require.JSONEq(t, `{"contentType":"application/json","jsonSchema":{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"array","items":{"type":"string"}}}`, metadata["restate.state.names"])
+	require.JSONEq(t, `{"contentType":"application/octet-stream"}`, metadata["restate.state.raw"])
+}
@@ -0,0 +1,235 @@
+package restate
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/restatedev/sdk-go/internal/restatecontext"
+)
+
+// StateTTLOption is an option for [SetWithTTL].
+type StateTTLOption = options.StateTTLOption
+
+// StateTTLHandlerName is the name of the handler added by [EnableStateTTL], which [SetWithTTL]
+// invokes with a delay to clear expired entries.
+const StateTTLHandlerName = "RestateExpireState"
+
+// stateTTLHeader prefixes the values set with [SetWithTTL], followed by their expiry in
+// nanoseconds since the Unix epoch.
+const stateTTLHeader = "\x00restate.ttl\x00"
+
+type stateExpiry struct {
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Callback  string    `json:"callback,omitempty"`
+}
+
+// ExpiredState is the input of the handler configured with [WithExpiryCallback], sent once
+// the entry has been cleared.
+type ExpiredState struct {
+	Key       string    `json:"key"`
+	ExpiredAt time.Time `json:"expiredAt"`
+}
+
+type withExpiryCallback struct {
+	handler string
+}
+
+var _ options.StateTTLOption = withExpiryCallback{}
+
+func (w withExpiryCallback) BeforeStateTTL(opts *options.StateTTLOptions) {
+	opts.ExpiryCallback = w.handler
+}
+
+// WithExpiryCallback sets a handler of the same virtual object to invoke, with an
+// [ExpiredState] input, when the entry expires.
+func WithExpiryCallback(handler string) withExpiryCallback {
+	return withExpiryCallback{handler}
+}
+
+// stateTTLContext is the context of the handlers of the virtual objects registered with
+// [EnableStateTTL], in which the expiry of the entries set with [SetWithTTL] is checked.
+type stateTTLContext struct {
+	restatecontext.Context
+}
+
+func (c stateTTLContext) Wrap(wrappedCtx context.Context) restatecontext.Context {
+	return stateTTLContext{c.Context.Wrap(wrappedCtx)}
+}
+
+// withStateTTL returns the context of a handler with the given options.
+func withStateTTL(ctx restatecontext.Context, opts *options.HandlerOptions) restatecontext.Context {
+	if !opts.StateTTL {
+		return ctx
+	}
+	return stateTTLContext{ctx}
+}
+
+func stateTTLEnabled(ctx ObjectSharedContext) bool {
+	_, ok := ctx.inner().(stateTTLContext)
+	return ok
+}
+
+func appendStateTTL(expiresAt time.Time, value []byte) []byte {
+	data := binary.BigEndian.AppendUint64([]byte(stateTTLHeader), uint64(expiresAt.UnixNano()))
+	return append(data, value...)
+}
+
+// cutStateTTL splits a value set with [SetWithTTL] into its expiry and the encoded value. It
+// returns false if the value has no expiry.
+func cutStateTTL(data []byte) (time.Time, []byte, bool) {
+	rest, ok := bytes.CutPrefix(data, []byte(stateTTLHeader))
+	if !ok || len(rest) < 8 {
+		return time.Time{}, data, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(rest))), rest[8:], true
+}
+
+// getRawState gets the encoded value for key. In the virtual objects registered with
+// [EnableStateTTL], the value is stripped of the expiry set with [SetWithTTL], which is
+// returned, and treated as absent once expired. Only these entries are checked for expiry.
+func getRawState(ctx ObjectSharedContext, key string) ([]byte, time.Time, bool, TerminalError) {
+	var data []byte
+	found, err := ctx.inner().Get(key, &data, WithBinary)
+	if err != nil || !found || !stateTTLEnabled(ctx) {
+		return data, time.Time{}, found, err
+	}
+	expiresAt, value, ok := cutStateTTL(data)
+	if !ok {
+		return data, time.Time{}, true, nil
+	}
+	now, err := now(ctx)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	if !now.Before(expiresAt) {
+		return nil, time.Time{}, false, nil
+	}
+	return value, expiresAt, true, nil
+}
+
+// getState gets the value for key into output, treating the entries set with [SetWithTTL]
+// as absent once expired in the virtual objects registered with [EnableStateTTL].
+func getState(ctx ObjectSharedContext, key string, output any, opts ...options.GetOption) (bool, TerminalError) {
+	if !stateTTLEnabled(ctx) {
+		return ctx.inner().Get(key, output, opts...)
+	}
+	o := options.GetOptions{}
+	for _, opt := range opts {
+		opt.BeforeGet(&o)
+	}
+	if o.Codec == nil {
+		o.Codec = encoding.JSONCodec
+	}
+
+	data, _, found, err := getRawState(ctx, key)
+	if err != nil || !found {
+		return false, err
+	}
+	if err := encoding.Unmarshal(o.Codec, data, output); err != nil {
+		panic(fmt.Errorf("failed to unmarshal Get state into output: %w", err))
+	}
+	return true, nil
+}
+
+// setStateWithTTL sets the value for key, encoded with codec, to expire at expiresAt.
+func setStateWithTTL(ctx ObjectContext, key string, value any, codec encoding.Codec, expiresAt time.Time) {
+	data, err := encoding.Marshal(codec, value)
+	if err != nil {
+		panic(fmt.Errorf("failed to marshal Set value: %w", err))
+	}
+	ctx.inner().Set(key, appendStateTTL(expiresAt, data), WithBinary)
+}
+
+// SetWithTTL sets a value against a key like [Set], and makes it expire after ttl. The expiry
+// is stored with the value. Once expired, the entry is treated as absent by [Get], and a
+// delayed invocation of the handler added by [EnableStateTTL] clears it from state; the
+// virtual object must therefore be registered with [EnableStateTTL].
+//
+// Setting the key again with SetWithTTL replaces its expiry, and setting it with [Set] makes
+// the entry persistent again. Use [RemoveTTL] to make it persistent keeping its value.
+func SetWithTTL[T any](ctx ObjectContext, key string, value T, ttl time.Duration, opts ...options.StateTTLOption) TerminalError {
+	o := options.StateTTLOptions{}
+	for _, opt := range withRegisteredCodec[T](opts) {
+		opt.BeforeStateTTL(&o)
+	}
+	if o.Codec == nil {
+		o.Codec = encoding.JSONCodec
+	}
+
+	now, err := now(ctx)
+	if err != nil {
+		return err
+	}
+	expiresAt := now.Add(ttl)
+
+	setStateWithTTL(ctx, key, value, o.Codec, expiresAt)
+	ObjectSend(ctx, ctx.Request().Service, Key(ctx), StateTTLHandlerName).
+		Send(stateExpiry{Key: key, ExpiresAt: expiresAt, Callback: o.ExpiryCallback}, WithDelay(ttl))
+	return nil
+}
+
+// ExpiresAt returns the time at which the entry set with [SetWithTTL] for key expires, or
+// false if the key has no TTL.
+func ExpiresAt(ctx ObjectSharedContext, key string) (time.Time, bool, TerminalError) {
+	var data []byte
+	if _, err := ctx.inner().Get(key, &data, WithBinary); err != nil {
+		return time.Time{}, false, err
+	}
+	expiresAt, _, ok := cutStateTTL(data)
+	return expiresAt, ok, nil
+}
+
+// RemoveTTL makes the entry for key persistent: the pending expiry is ignored and the value
+// is kept.
+func RemoveTTL(ctx ObjectContext, key string) TerminalError {
+	var data []byte
+	if _, err := ctx.inner().Get(key, &data, WithBinary); err != nil {
+		return err
+	}
+	if _, value, ok := cutStateTTL(data); ok {
+		ctx.inner().Set(key, value, WithBinary)
+	}
+	return nil
+}
+
+// EnableStateTTL adds to the virtual object definition the handler clearing the entries
+// set with [SetWithTTL] once they expire, and returns the definition. From then on, [Get]
+// in the handlers of the virtual object treats expired entries as absent, which costs a Run
+// for each entry set with SetWithTTL. It panics if definition is not a virtual object
+// created with [NewObject] or [Reflect].
+func EnableStateTTL(definition ServiceDefinition) ServiceDefinition {
+	def, ok := definition.(*object)
+	if !ok {
+		panic(fmt.Sprintf("state TTL can only be enabled on virtual objects, %s is a %s", definition.Name(), definition.Type()))
+	}
+	def.options.StateTTL = true
+	for _, handler := range def.handlers {
+		handler.GetOptions().StateTTL = true
+	}
+	return def.Handler(StateTTLHandlerName, NewObjectHandler(expireState, WithJSON))
+}
+
+func expireState(ctx ObjectContext, expiry stateExpiry) (Void, error) {
+	var data []byte
+	if _, err := ctx.inner().Get(expiry.Key, &data, WithBinary); err != nil {
+		return Void{}, err
+	}
+	expiresAt, _, ok := cutStateTTL(data)
+	if !ok || !expiresAt.Equal(expiry.ExpiresAt) {
+		// The TTL was removed or the entry was set again
+		return Void{}, nil
+	}
+
+	Clear(ctx, expiry.Key)
+	if expiry.Callback != "" {
+		ObjectSend(ctx, ctx.Request().Service, Key(ctx), expiry.Callback).
+			Send(ExpiredState{Key: expiry.Key, ExpiredAt: expiry.ExpiresAt})
+	}
+	return Void{}, nil
+}
@@ -25,3 +25,11 @@ func After(ctx Context, d time.Duration, opts ...options.SleepOption) AfterFutur
 // AfterFuture is returned by the After operation which allows you to do other work concurrently
 // with the sleep.
 type AfterFuture = restatecontext.AfterFuture
+
+// now returns the current time, recorded in the journal so that replays observe the same
+// value. The result is truncated to milliseconds, the resolution of Restate timers.
+func now(ctx Context) (time.Time, TerminalError) {
+	return Run(ctx, func(RunContext) (time.Time, error) {
+		return time.Now().Truncate(time.Millisecond), nil
+	}, WithName("now"))
+}
@@ -0,0 +1,293 @@
+package restate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/restatedev/sdk-go/internal/options"
+)
+
+// Validator validates decoded handler inputs. Set it with [WithValidator].
+type Validator = options.Validator
+
+// FieldViolationsMetadataKey is the terminal error metadata key under which the field
+// violations of an invalid handler input are stored, as a JSON list of [FieldViolation].
+const FieldViolationsMetadataKey = "restate.fieldViolations"
+
+// FieldViolation describes why a field of a handler input is invalid. Field is the path of
+// the field, such as items[0].name, and is empty for violations of the input as a whole.
+type FieldViolation struct {
+	Field       string `json:"field,omitempty"`
+	Description string `json:"description"`
+}
+
+// ValidationError is an error made of field violations, which a Validate method or a
+// [Validator] can return to report several invalid fields.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		if v.Field == "" {
+			descriptions = append(descriptions, v.Description)
+		} else {
+			descriptions = append(descriptions, v.Field+": "+v.Description)
+		}
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// FieldViolations returns the field violations attached to the terminal error returned for
+// an invalid handler input, or nil if err carries none.
+func FieldViolations(err error) []FieldViolation {
+	terminal := AsTerminalError(err)
+	if terminal == nil {
+		return nil
+	}
+	data := terminal.Metadata().Get(FieldViolationsMetadataKey)
+	if data == "" {
+		return nil
+	}
+	var violations []FieldViolation
+	if json.Unmarshal([]byte(data), &violations) != nil {
+		return nil
+	}
+	return violations
+}
+
+type withValidator struct {
+	validator Validator
+}
+
+var _ options.HandlerOption = withValidator{}
+var _ options.ServiceDefinitionOption = withValidator{}
+
+func (w withValidator) BeforeHandler(opts *options.HandlerOptions) {
+	opts.Validator = w.validator
+}
+
+func (w withValidator) BeforeServiceDefinition(opts *options.ServiceDefinitionOptions) {
+	opts.DefaultValidator = w.validator
+}
+
+// WithValidator validates the decoded inputs of a handler with validator, after their Validate
+// method if they have one. When passed to a service definition, it applies to all the
+// handlers which don't set their own.
+func WithValidator(validator Validator) withValidator {
+	return withValidator{validator}
+}
+
+// validateInput checks a decoded handler input, given as a pointer, before the handler runs.
+// Inputs with a Validate() error method are validated first, then validator, if any, is
+// applied. Failures are returned as terminal errors with code 400, carrying the field
+// violations in their metadata.
+func validateInput(validator Validator, input any) error {
+	if _, ok := input.(*Void); ok {
+		return nil
+	}
+	var err error
+	if v, ok := input.(interface{ Validate() error }); ok {
+		err = v.Validate()
+	} else if v, ok := reflect.ValueOf(input).Elem().Interface().(interface{ Validate() error }); ok && !isNilPointer(v) {
+		err = v.Validate()
+	}
+	if err == nil && validator != nil {
+		err = validator.Validate(reflect.ValueOf(input).Elem().Interface())
+	}
+	if err == nil {
+		return nil
+	}
+	if IsTerminalError(err) {
+		// The validation decided the failure
+		return err
+	}
+
+	var validationErr *ValidationError
+	var violations []FieldViolation
+	if errors.As(err, &validationErr) {
+		violations = validationErr.Violations
+	} else {
+		violations = []FieldViolation{{Description: err.Error()}}
+	}
+	data, marshalErr := json.Marshal(violations)
+	if marshalErr != nil {
+		return ToTerminalError(fmt.Errorf("invalid handler input: %w", err), WithErrorCode(http.StatusBadRequest))
+	}
+	return ToTerminalError(fmt.Errorf("invalid handler input: %w", err),
+		WithErrorCode(http.StatusBadRequest), WithMetadata(FieldViolationsMetadataKey, string(data)))
+}
+
+func isNilPointer(v any) bool {
+	value := reflect.ValueOf(v)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
+
+// StructTagValidator is a [Validator] checking the rules in the `validate` struct tags of the
+// input fields, recursing into nested structs, pointers, slices and arrays. Fields are named
+// after their JSON names. Rules are separated by commas:
+//
+//   - required: the field must not be the zero value
+//   - min=n, max=n: bounds of numbers, or of the length of strings, slices and maps
+//   - len=n: exact length of strings, slices and maps
+//   - oneof=a b c: the field must be one of the listed values
+//
+// For example:
+//
+//	type Order struct {
+//		ID       string `json:"id" validate:"required"`
+//		Quantity int    `json:"quantity" validate:"min=1,max=100"`
+//		Currency string `json:"currency" validate:"oneof=EUR USD"`
+//	}
+var StructTagValidator Validator = structTagValidator{}
+
+type structTagValidator struct{}
+
+func (structTagValidator) Validate(input any) error {
+	var violations []FieldViolation
+	validateValue(reflect.ValueOf(input), "", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func validateValue(value reflect.Value, path string, violations *[]FieldViolation) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		typ := value.Type()
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := joinFieldPath(path, field)
+			if rules, ok := field.Tag.Lookup("validate"); ok {
+				for _, rule := range strings.Split(rules, ",") {
+					if description := checkRule(value.Field(i), strings.TrimSpace(rule)); description != "" {
+						*violations = append(*violations, FieldViolation{Field: fieldPath, Description: description})
+					}
+				}
+			}
+			validateValue(value.Field(i), fieldPath, violations)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			validateValue(value.Index(i), path+"["+strconv.Itoa(i)+"]", violations)
+		}
+	}
+}
+
+func joinFieldPath(path string, field reflect.StructField) string {
+	name := field.Name
+	if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		name = tag
+	}
+	if field.Anonymous && field.Tag.Get("json") == "" {
+		// Embedded fields are flattened in JSON
+		return path
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func checkRule(value reflect.Value, rule string) string {
+	name, param, _ := strings.Cut(rule, "=")
+	switch name {
+	case "":
+		return ""
+	case "required":
+		if value.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			// Absent optional fields are only checked by required
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	switch name {
+	case "min", "max", "len":
+		bound, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return fmt.Sprintf("has an invalid %s rule %q", name, param)
+		}
+		measure, isLength, ok := measureValue(value)
+		if !ok {
+			return fmt.Sprintf("does not support the %s rule", name)
+		}
+		what := "must be"
+		if isLength {
+			what = "must have a length"
+		}
+		switch {
+		case name == "min" && measure < bound:
+			return fmt.Sprintf("%s of at least %s", what, param)
+		case name == "max" && measure > bound:
+			return fmt.Sprintf("%s of at most %s", what, param)
+		case name == "len" && measure != bound:
+			return fmt.Sprintf("%s of exactly %s", what, param)
+		}
+		return ""
+	case "oneof":
+		allowed := strings.Fields(param)
+		var actual string
+		switch value.Kind() {
+		case reflect.String:
+			actual = value.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			actual = strconv.FormatInt(value.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			actual = strconv.FormatUint(value.Uint(), 10)
+		default:
+			return "does not support the oneof rule"
+		}
+		if !slices.Contains(allowed, actual) {
+			return fmt.Sprintf("must be one of %s", strings.Join(allowed, ", "))
+		}
+		return ""
+	default:
+		return fmt.Sprintf("has an unknown validation rule %q", name)
+	}
+}
+
+// measureValue returns the number compared by min, max and len rules: the value of numbers,
+// and the length of strings, slices and maps.
+func measureValue(value reflect.Value) (measure float64, isLength bool, ok bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), true, true
+	default:
+		return 0, false, false
+	}
+}
@@ -0,0 +1,149 @@
+package restate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal/options"
+)
+
+// Names of the handlers added by [EnableWorkflowPromises].
+const (
+	// ResolvePromiseHandlerName resolves a durable promise of the workflow, see
+	// [WorkflowHandle.ResolvePromise].
+	ResolvePromiseHandlerName = "RestateResolvePromise"
+	// RejectPromiseHandlerName rejects a durable promise of the workflow, see
+	// [WorkflowHandle.RejectPromise].
+	RejectPromiseHandlerName = "RestateRejectPromise"
+)
+
+// WorkflowHandle is a handle on a workflow started from a handler with [StartWorkflow]. All
+// its operations are durable, like the other calls made with the handler context.
+type WorkflowHandle[O any] interface {
+	// Invocation is the invocation of the Run handler of the workflow.
+	Invocation
+	// Service returns the name of the workflow.
+	Service() string
+	// WorkflowID returns the id of the workflow.
+	WorkflowID() string
+	// Attach returns a future completed with the output of the Run handler of the workflow,
+	// once it completes.
+	Attach() AttachFuture[O]
+	// Output blocks until the Run handler of the workflow completes, and returns its output or
+	// the terminal error it failed with.
+	Output() (O, TerminalError)
+	// ResolvePromise resolves the durable promise of the workflow with the given name,
+	// returning an error if it was already completed. The value is encoded with the codec set
+	// with [WithCodec], or else the one registered for its type, or else JSON, which must match
+	// the codec the workflow reads the promise with. The workflow must be registered with
+	// [EnableWorkflowPromises].
+	ResolvePromise(name string, value any, opts ...options.PromiseOption) TerminalError
+	// RejectPromise rejects the durable promise of the workflow with the given name,
+	// returning an error if it was already completed. The workflow must be registered with
+	// [EnableWorkflowPromises].
+	RejectPromise(name string, reason error) TerminalError
+}
+
+// StartWorkflow starts the workflow with the given name and id, invoking its Run handler with
+// input, and returns a typed handle on it:
+//
+//	handle := restate.StartWorkflow[SignupInput, SignupOutput](ctx, "Signup", userID, input)
+//	if err := handle.ResolvePromise("email-verified", true); err != nil {
+//		return err
+//	}
+//	output, err := handle.Output()
+//
+// Use [WorkflowHandlerClient] to call the shared handlers of the workflow.
+func StartWorkflow[I any, O any](ctx Context, name string, id string, input I, opts ...options.SendOption) WorkflowHandle[O] {
+	invocation := WorkflowSend(ctx, name, id, "Run").Send(input, opts...)
+	return workflowHandle[O]{ctx: ctx, service: name, workflowID: id, Invocation: invocation}
+}
+
+// WorkflowHandlerClient returns a typed client for the handler of the workflow of handle, for
+// example to call its shared handlers:
+//
+//	status, err := restate.WorkflowHandlerClient[Status](ctx, handle, "GetStatus").Request(restate.Void{})
+func WorkflowHandlerClient[O any, W any](ctx Context, handle WorkflowHandle[W], handler string, opts ...options.ClientOption) Client[any, O] {
+	return Workflow[O](ctx, handle.Service(), handle.WorkflowID(), handler, opts...)
+}
+
+type workflowHandle[O any] struct {
+	Invocation
+	ctx        Context
+	service    string
+	workflowID string
+}
+
+func (h workflowHandle[O]) Service() string    { return h.service }
+func (h workflowHandle[O]) WorkflowID() string { return h.workflowID }
+
+func (h workflowHandle[O]) Attach() AttachFuture[O] {
+	return AttachInvocation[O](h.ctx, h.GetInvocationId())
+}
+
+func (h workflowHandle[O]) Output() (O, TerminalError) {
+	return h.Attach().Response()
+}
+
+func (h workflowHandle[O]) ResolvePromise(name string, value any, opts ...options.PromiseOption) TerminalError {
+	o := options.PromiseOptions{}
+	for _, opt := range opts {
+		opt.BeforePromise(&o)
+	}
+	if o.Codec == nil {
+		o.Codec = encoding.DefaultCodecFor(value)
+	}
+	data, err := encoding.Marshal(o.Codec, value)
+	if err != nil {
+		return ToTerminalError(fmt.Errorf("failed to marshal value of promise %s: %w", name, err))
+	}
+	_, requestErr := Workflow[Void](h.ctx, h.service, h.workflowID, ResolvePromiseHandlerName).
+		Request(workflowPromiseCompletion{Name: name, Value: data})
+	return requestErr
+}
+
+func (h workflowHandle[O]) RejectPromise(name string, reason error) TerminalError {
+	code := Code(500)
+	if terminal := AsTerminalError(reason); terminal != nil {
+		code = terminal.Code()
+	}
+	_, err := Workflow[Void](h.ctx, h.service, h.workflowID, RejectPromiseHandlerName).
+		Request(workflowPromiseCompletion{Name: name, Code: code, Message: reason.Error()})
+	return err
+}
+
+type workflowPromiseCompletion struct {
+	Name string `json:"name"`
+	// Value is the promise value, encoded by the caller.
+	Value   []byte `json:"value,omitempty"`
+	Code    Code   `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// EnableWorkflowPromises adds to the workflow definition the shared handlers resolving and
+// rejecting its durable promises, used by [WorkflowHandle.ResolvePromise] and
+// [WorkflowHandle.RejectPromise], and returns the definition. It panics if definition is not
+// a workflow created with [NewWorkflow] or [Reflect].
+func EnableWorkflowPromises(definition ServiceDefinition) ServiceDefinition {
+	def, ok := definition.(*workflow)
+	if !ok {
+		panic(fmt.Sprintf("workflow promises can only be enabled on workflows, %s is a %s", definition.Name(), definition.Type()))
+	}
+	return def.
+		Handler(ResolvePromiseHandlerName, NewWorkflowSharedHandler(resolveWorkflowPromise, WithJSON)).
+		Handler(RejectPromiseHandlerName, NewWorkflowSharedHandler(rejectWorkflowPromise, WithJSON))
+}
+
+func resolveWorkflowPromise(ctx WorkflowSharedContext, completion workflowPromiseCompletion) (Void, error) {
+	return Void{}, Promise[[]byte](ctx, completion.Name, WithBinary).Resolve(completion.Value)
+}
+
+func rejectWorkflowPromise(ctx WorkflowSharedContext, completion workflowPromiseCompletion) (Void, error) {
+	code := completion.Code
+	if code == 0 {
+		code = 500
+	}
+	return Void{}, Promise[Void](ctx, completion.Name).
+		Reject(ToTerminalError(errors.New(completion.Message), WithErrorCode(code)))
+}
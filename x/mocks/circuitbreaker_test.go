@@ -0,0 +1,232 @@
+package mocks_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/internal/errors"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const circuitBreakerConfig = `{"failureThreshold":2,"openTimeout":10000000000}`
+
+// newCircuitBreakerContext returns the context of the circuit breaker named payments, and a
+// function mocking the reads of its configuration and of the given state, if any.
+func newCircuitBreakerContext(t *testing.T) (*mocks.MockContext, func(state string)) {
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	mockCtx.EXPECT().Key().Return("payments").Maybe()
+	return mockCtx, func(state string) {
+		getJSONAndReturn(mockCtx, "config", circuitBreakerConfig, mock.Anything).Once()
+		if state == "" {
+			mockCtx.EXPECT().Get("state", mock.Anything, mock.Anything).Return(false, nil).Once()
+		} else {
+			getJSONAndReturn(mockCtx, "state", state, mock.Anything).Once()
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	handlers := restate.NewCircuitBreakerService().Handlers()
+	mockCtx, load := newCircuitBreakerContext(t)
+	call := func(handler string, input string) []byte {
+		output, err := handlers[handler].Call(mockCtx, []byte(input))
+		require.NoError(t, err)
+		return output
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := handlers["Configure"].Call(mockCtx, []byte(`{"failureThreshold":-1}`))
+	require.Equal(t, restate.Code(400), restate.AsTerminalError(err).Code())
+	mockCtx.EXPECT().Set("config", &restate.CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: 10 * time.Second}, mock.Anything).Once()
+	call("Configure", circuitBreakerConfig)
+
+	// Closed circuits let calls through, and successes reset the failures
+	mockCtx.EXPECT().Get("state", mock.Anything, mock.Anything).Return(false, nil).Once()
+	require.JSONEq(t, `{}`, string(call("Allow", `null`)))
+
+	load("")
+	mockCtx.EXPECT().Set("state", matchJSON(`{"state":"closed","failures":1,"generation":0}`), mock.Anything).Once()
+	call("Record", `{"success":false}`)
+
+	// Cancelled calls are not recorded
+	load(`{"state":"closed","failures":1,"generation":0}`)
+	call("Record", `{"success":false,"released":true}`)
+
+	load(`{"state":"closed","failures":1,"generation":0}`)
+	mockCtx.EXPECT().Set("state", matchJSON(`{"state":"closed","generation":0}`), mock.Anything).Once()
+	call("Record", `{"success":true}`)
+
+	// Consecutive failures open the circuit
+	open := `{"state":"open","failures":2,"openedAt":"2024-01-01T00:00:00Z","generation":1}`
+	load(`{"state":"closed","failures":1,"generation":0}`)
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Timeout").
+		MockSend(matchJSON(`{"generation":1}`), restate.WithDelay(10*time.Second))
+	mockCtx.EXPECT().Set("state", matchJSON(open), mock.Anything).Once()
+	call("Record", `{"success":false}`)
+
+	load(open)
+	mockCtx.EXPECT().GetAndReturn("waiters", []string{"w1"}, mock.Anything).Once()
+	var status restate.CircuitBreakerStatus
+	require.NoError(t, json.Unmarshal(call("Status", `null`), &status))
+	require.Equal(t, restate.CircuitBreakerStatus{
+		Config:   restate.CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: 10 * time.Second},
+		State:    restate.CircuitOpen,
+		Failures: 2,
+		OpenedAt: start,
+		Waiting:  1,
+	}, status)
+
+	getJSONAndReturn(mockCtx, "state", open, mock.Anything).Once()
+	_, err = handlers["Allow"].Call(mockCtx, []byte(`null`))
+	require.True(t, restate.IsCircuitOpen(err))
+	require.Equal(t, restate.Code(503), restate.AsTerminalError(err).Code())
+
+	// Waiting calls are queued while the circuit is open
+	load(open)
+	mockCtx.EXPECT().GetAndReturn("waiters", []string{"w1"}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("waiters", []string{"w1", "w2"}, mock.Anything).Once()
+	call("Wait", `{"awakeableId":"w2"}`)
+
+	// Stale timeouts are ignored
+	load(open)
+	call("Timeout", `{"generation":0}`)
+
+	// The circuit becomes half-open, and lets the first waiting call through as a probe
+	load(open)
+	mockCtx.EXPECT().GetAndReturn("waiters", []string{"w1", "w2"}, mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Timeout").
+		MockSend(matchJSON(`{"generation":2}`), restate.WithDelay(10*time.Second))
+	mockCtx.EXPECT().ResolveAwakeable("w1", matchJSON(`{"probe":true,"generation":2}`)).Once()
+	mockCtx.EXPECT().Set("waiters", []string{"w2"}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("state", matchJSON(`{"state":"half_open","failures":2,"openedAt":"2024-01-01T00:00:00Z","generation":2,"probing":true}`), mock.Anything).Once()
+	call("Timeout", `{"generation":1}`)
+
+	getJSONAndReturn(mockCtx, "state", `{"state":"half_open","failures":2,"openedAt":"2024-01-01T00:00:00Z","generation":2,"probing":true}`, mock.Anything).Once()
+	_, err = handlers["Allow"].Call(mockCtx, []byte(`null`))
+	require.True(t, restate.IsCircuitOpen(err))
+
+	// A failed probe opens the circuit again
+	load(`{"state":"half_open","failures":2,"openedAt":"2024-01-01T00:00:00Z","generation":2,"probing":true}`)
+	mockCtx.EXPECT().RunAndReturn(start.Add(time.Minute), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Timeout").
+		MockSend(matchJSON(`{"generation":3}`), restate.WithDelay(10*time.Second))
+	mockCtx.EXPECT().Set("state", matchJSON(`{"state":"open","failures":2,"openedAt":"2024-01-01T00:01:00Z","generation":3}`), mock.Anything).Once()
+	call("Record", `{"success":false,"probe":true,"generation":2}`)
+}
+
+func TestCircuitBreakerProbes(t *testing.T) {
+	handlers := restate.NewCircuitBreakerService().Handlers()
+	mockCtx, load := newCircuitBreakerContext(t)
+	call := func(handler string, input string) []byte {
+		output, err := handlers[handler].Call(mockCtx, []byte(input))
+		require.NoError(t, err)
+		return output
+	}
+	halfOpen := `{"state":"half_open","failures":2,"openedAt":"2024-01-01T00:00:00Z","generation":3}`
+	probing := func(generation string) string {
+		return `{"state":"half_open","failures":2,"openedAt":"2024-01-01T00:00:00Z","generation":` + generation + `,"probing":true}`
+	}
+
+	// Half-open circuits grant the probe with the exclusive handler
+	getJSONAndReturn(mockCtx, "state", halfOpen, mock.Anything).Once()
+	requestJSONAndReturn(mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Probe"),
+		restate.Void{}, `{"probe":true,"generation":4}`).Once()
+	require.JSONEq(t, `{"probe":true,"generation":4}`, string(call("Allow", `null`)))
+
+	load(halfOpen)
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Timeout").
+		MockSend(matchJSON(`{"generation":4}`), restate.WithDelay(10*time.Second))
+	mockCtx.EXPECT().Set("state", matchJSON(probing("4")), mock.Anything).Once()
+	require.JSONEq(t, `{"probe":true,"generation":4}`, string(call("Probe", `null`)))
+
+	// The probe takes too long, another one is let through
+	load(probing("4"))
+	mockCtx.EXPECT().GetAndReturn("waiters", []string{"w2", "w3"}, mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Timeout").
+		MockSend(matchJSON(`{"generation":5}`), restate.WithDelay(10*time.Second))
+	mockCtx.EXPECT().ResolveAwakeable("w2", matchJSON(`{"probe":true,"generation":5}`)).Once()
+	mockCtx.EXPECT().Set("waiters", []string{"w3"}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("state", matchJSON(probing("5")), mock.Anything).Once()
+	call("Timeout", `{"generation":4}`)
+
+	// The outcome of the first probe is ignored
+	load(probing("5"))
+	call("Record", `{"success":true,"probe":true,"generation":4}`)
+
+	// A cancelled probe lets the waiting call through as a probe
+	load(probing("5"))
+	mockCtx.EXPECT().GetAndReturn("waiters", []string{"w3"}, mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Timeout").
+		MockSend(matchJSON(`{"generation":6}`), restate.WithDelay(10*time.Second))
+	mockCtx.EXPECT().ResolveAwakeable("w3", matchJSON(`{"probe":true,"generation":6}`)).Once()
+	mockCtx.EXPECT().Clear("waiters").Once()
+	mockCtx.EXPECT().Set("state", matchJSON(probing("6")), mock.Anything).Once()
+	call("Record", `{"success":false,"released":true,"probe":true,"generation":5}`)
+
+	// A successful probe closes the circuit, letting all the waiting calls through
+	load(probing("6"))
+	mockCtx.EXPECT().GetAndReturn("waiters", []string{"w4", "w5"}, mock.Anything).Once()
+	mockCtx.EXPECT().ResolveAwakeable("w4", matchJSON(`{}`)).Once()
+	mockCtx.EXPECT().ResolveAwakeable("w5", matchJSON(`{}`)).Once()
+	mockCtx.EXPECT().Clear("waiters").Once()
+	mockCtx.EXPECT().Set("state", matchJSON(`{"state":"closed","openedAt":"2024-01-01T00:00:00Z","generation":7}`), mock.Anything).Once()
+	call("Record", `{"success":true,"probe":true,"generation":6}`)
+}
+
+func TestCircuitBreakerOpen(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+
+	// Open circuits fail fast, without running the function
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Allow").
+		Request(restate.Void{}, mock.Anything).
+		Return(errors.NewTerminalError("circuit breaker payments is open", errors.WithCode(503), errors.WithMetadata(map[string]string{
+			restate.CircuitBreakerMetadataKey: "payments",
+		}))).Once()
+	err := restate.RunVoid(ctx, func(restate.RunContext) error {
+		return nil
+	}, restate.WithCircuitBreaker("payments"))
+	require.True(t, restate.IsCircuitOpen(err))
+}
+
+func TestCircuitBreakerCancellation(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+
+	requestJSONAndReturn(mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Allow"),
+		restate.Void{}, `{}`).Once()
+	mockCtx.EXPECT().Run(mock.Anything, mock.Anything, restate.WithCircuitBreaker("payments")).
+		Return(errors.NewCancellationError()).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Record").
+		MockSend(matchJSON(`{"success":false,"released":true}`))
+	err := restate.RunVoid(ctx, func(restate.RunContext) error {
+		return nil
+	}, restate.WithCircuitBreaker("payments"))
+	require.True(t, restate.IsCancellation(err))
+}
+
+func TestCircuitBreakerRetryLimits(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+
+	requestJSONAndReturn(mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Allow"),
+		restate.Void{}, `{}`).Once()
+	mockCtx.EXPECT().Run(mock.Anything, mock.Anything, restate.WithCircuitBreaker("payments"), restate.WithMaxRetryAttempts(1)).
+		Return(errors.NewTerminalError("unavailable", errors.WithCode(503), errors.WithMetadata(map[string]string{
+			errors.CircuitBreakerFailureMetadataKey: "payments",
+			"region":                                "eu",
+		}))).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.CircuitBreakerServiceName, "payments", "Record").
+		MockSend(matchJSON(`{"success":false}`))
+	err := restate.RunVoid(ctx, func(restate.RunContext) error {
+		return nil
+	}, restate.WithCircuitBreaker("payments"), restate.WithMaxRetryAttempts(1))
+	require.Equal(t, restate.Code(503), err.Code())
+	require.Equal(t, map[string]string{"region": "eu"}, err.Metadata().ToMap())
+}
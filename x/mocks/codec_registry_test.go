@@ -0,0 +1,132 @@
+package mocks_test
+
+import (
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type registeredPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func init() {
+	encoding.Register[registeredPoint](encoding.CBORCodec)
+}
+
+func contentType(codec encoding.Codec) string {
+	return codec.(encoding.CodecMetadata).ContentType()
+}
+
+// setContentType returns the content type of the codec selected by the options of a 'Set'
+// call.
+func setContentType(opts []options.SetOption) string {
+	o := options.SetOptions{}
+	for _, opt := range opts {
+		opt.BeforeSet(&o)
+	}
+	return contentType(o.Codec)
+}
+
+// getContentType returns the content type of the codec selected by the options of a 'Get'
+// call.
+func getContentType(opts []options.GetOption) string {
+	o := options.GetOptions{}
+	for _, opt := range opts {
+		opt.BeforeGet(&o)
+	}
+	return contentType(o.Codec)
+}
+
+func TestRegisteredCodec(t *testing.T) {
+	cbor := func(v any) []byte {
+		data, err := encoding.CBORCodec.Marshal(v)
+		require.NoError(t, err)
+		return data
+	}
+
+	t.Run("state", func(t *testing.T) {
+		mockCtx := mocks.NewMockContext(t)
+		ctx := restate.WithMockContext(mockCtx)
+
+		mockCtx.EXPECT().Set("point", registeredPoint{X: 1, Y: 2}, mock.Anything).
+			Run(func(_ string, _ any, opts ...options.SetOption) {
+				require.Equal(t, "application/cbor", setContentType(opts))
+			}).Once()
+		restate.Set(ctx, "point", registeredPoint{X: 1, Y: 2})
+
+		mockCtx.EXPECT().GetAndReturn("point", registeredPoint{X: 1, Y: 2}, mock.Anything).
+			Run(func(_ string, _ any, opts ...options.GetOption) {
+				require.Equal(t, "application/cbor", getContentType(opts))
+			}).Once()
+		point, err := restate.Get[registeredPoint](ctx, "point")
+		require.NoError(t, err)
+		require.Equal(t, registeredPoint{X: 1, Y: 2}, point)
+
+		// The codec of the options wins over the registered one
+		mockCtx.EXPECT().Set("json", registeredPoint{X: 3}, mock.Anything, mock.Anything).
+			Run(func(_ string, _ any, opts ...options.SetOption) {
+				require.Equal(t, "application/json", setContentType(opts))
+			}).Once()
+		restate.Set(ctx, "json", registeredPoint{X: 3}, restate.WithJSON)
+
+		mockCtx.EXPECT().Set("count", 1).Once()
+		restate.Set(ctx, "count", 1)
+	})
+
+	t.Run("state key", func(t *testing.T) {
+		mockCtx := mocks.NewMockContext(t)
+		ctx := restate.WithMockContext(mockCtx)
+
+		mockCtx.EXPECT().Set("point", registeredPoint{X: 1}, mock.Anything).
+			Run(func(_ string, _ any, opts ...options.SetOption) {
+				require.Equal(t, "application/cbor", setContentType(opts))
+			}).Once()
+		restate.NewStateKey[registeredPoint]("point").Set(ctx, registeredPoint{X: 1})
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		handler := restate.NewServiceHandler(func(ctx restate.Context, input registeredPoint) (registeredPoint, error) {
+			return registeredPoint{X: input.Y, Y: input.X}, nil
+		})
+		restate.NewService("Points").Handler("Swap", handler)
+		require.Equal(t, contentType(encoding.CBORCodec), contentType(handler.GetOptions().InputCodec))
+
+		mockCtx := mocks.NewMockContext(t)
+		mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+		output, err := handler.Call(mockCtx, cbor(registeredPoint{X: 1, Y: 2}))
+		require.NoError(t, err)
+		require.Equal(t, cbor(registeredPoint{X: 2, Y: 1}), output)
+	})
+
+	t.Run("explicit codecs win", func(t *testing.T) {
+		handler := restate.NewServiceHandler(func(ctx restate.Context, input registeredPoint) (registeredPoint, error) {
+			return input, nil
+		}, restate.WithOutputCodec(encoding.MsgPackCodec))
+		restate.NewService("Points").Handler("Echo", handler)
+		require.Equal(t, contentType(encoding.CBORCodec), contentType(handler.GetOptions().InputCodec))
+		require.Equal(t, contentType(encoding.MsgPackCodec), contentType(handler.GetOptions().OutputCodec))
+
+		handler = restate.NewServiceHandler(func(ctx restate.Context, input registeredPoint) (registeredPoint, error) {
+			return input, nil
+		})
+		restate.NewService("JSONPoints", restate.WithJSON).Handler("Echo", handler)
+		require.Equal(t, contentType(encoding.JSONCodec), contentType(handler.GetOptions().InputCodec))
+		require.Equal(t, contentType(encoding.JSONCodec), contentType(handler.GetOptions().OutputCodec))
+	})
+
+	t.Run("unregistered types", func(t *testing.T) {
+		handler := restate.NewServiceHandler(func(ctx restate.Context, input string) (registeredPoint, error) {
+			return registeredPoint{}, nil
+		})
+		restate.NewService("Points").Handler("Zero", handler)
+		require.Equal(t, contentType(encoding.JSONCodec), contentType(handler.GetOptions().InputCodec))
+		require.Equal(t, contentType(encoding.CBORCodec), contentType(handler.GetOptions().OutputCodec))
+	})
+}
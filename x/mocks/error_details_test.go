@@ -0,0 +1,98 @@
+package mocks_test
+
+import (
+	"errors"
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+type insufficientFunds struct {
+	Missing int `json:"missing"`
+}
+
+func (e insufficientFunds) Error() string {
+	return "insufficient funds"
+}
+
+// thumbnail is registered with thumbnailCodec, encoding it as raw bytes.
+type thumbnail struct {
+	Data []byte
+}
+
+type thumbnailCodec struct{}
+
+func (thumbnailCodec) Marshal(v any) ([]byte, error) {
+	return v.(thumbnail).Data, nil
+}
+
+func (thumbnailCodec) Unmarshal(data []byte, v any) error {
+	v.(*thumbnail).Data = data
+	return nil
+}
+
+type outOfStock struct {
+	SKU string `json:"sku"`
+}
+
+func (e outOfStock) Error() string {
+	return e.SKU + " is out of stock"
+}
+
+func TestErrorDetails(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	handler := restate.NewServiceHandler(func(ctx restate.Context, amount int) (restate.Void, error) {
+		return restate.Void{}, restate.TerminalErrorWithDetails("insufficient funds", insufficientFunds{Missing: amount}, nil, restate.WithErrorCode(409))
+	})
+	restate.NewService("Bank").Handler("Withdraw", handler)
+
+	_, err := handler.Call(mockCtx, []byte(`42`))
+	require.Equal(t, restate.Code(409), restate.AsTerminalError(err).Code())
+	require.Equal(t, "insufficient funds", err.Error())
+
+	details, ok := restate.ErrorDetails[insufficientFunds](err)
+	require.True(t, ok)
+	require.Equal(t, insufficientFunds{Missing: 42}, details)
+
+	// Details of another type are not decoded
+	_, ok = restate.ErrorDetails[outOfStock](err)
+	require.False(t, ok)
+	_, ok = restate.ErrorDetails[insufficientFunds](restate.TerminalErrorf("no details"))
+	require.False(t, ok)
+
+	var asDetails insufficientFunds
+	require.True(t, errors.As(err, &asDetails))
+	require.Equal(t, 42, asDetails.Missing)
+	var terminal restate.TerminalError
+	require.True(t, errors.As(err, &terminal))
+}
+
+func TestRegisteredErrorDetails(t *testing.T) {
+	restate.RegisterErrorDetails[outOfStock]("shop.OutOfStock", nil)
+	err := restate.TerminalErrorWithDetails("out of stock", outOfStock{SKU: "abc"}, nil)
+	require.Equal(t, "shop.OutOfStock", err.Metadata().Get("restate.errorDetailsType"))
+
+	var details outOfStock
+	require.ErrorAs(t, err, &details)
+	require.Equal(t, "abc", details.SKU)
+
+	// Binary details survive the string metadata
+	binary := restate.TerminalErrorWithDetails("binary", []byte{0xff, 0x00}, encoding.BinaryCodec)
+	_, ok := restate.ErrorDetails[[]byte](binary)
+	require.False(t, ok, "decoded with the default JSON codec")
+	restate.RegisterErrorDetails[thumbnail]("shop.Thumbnail", thumbnailCodec{})
+	binary = restate.TerminalErrorWithDetails("binary", thumbnail{Data: []byte{0xff, 0x00}}, nil)
+	decoded, ok := restate.ErrorDetails[thumbnail](binary)
+	require.True(t, ok)
+	require.Equal(t, thumbnail{Data: []byte{0xff, 0x00}}, decoded)
+}
+
+func TestUnencodableErrorDetails(t *testing.T) {
+	require.PanicsWithError(t, "failed to encode error details: json: unsupported type: chan int", func() {
+		restate.TerminalErrorWithDetails("unencodable", make(chan int), nil)
+	})
+}
@@ -51,9 +51,10 @@ func NewMockContext(t *testing.T) *MockContext {
 	return mock
 }
 
-// RunAndReturn is a helper method to mock a typical 'Run' call; return a concrete value or an error
-func (_e *MockContext_Expecter) RunAndReturn(value any, err restate.TerminalError) *MockContext_Run_Call {
-	return _e.Run(mock.Anything, mock.AnythingOfType(pointerType(value))).RunAndReturn(func(f func(restatecontext.RunContext) (any, error), i any, ro ...options.RunOption) restate.TerminalError {
+// RunAndReturn is a helper method to mock a typical 'Run' call; return a concrete value or an error.
+// The options passed to Run, if any, must be matched by opts
+func (_e *MockContext_Expecter) RunAndReturn(value any, err restate.TerminalError, opts ...interface{}) *MockContext_Run_Call {
+	return _e.Run(mock.Anything, mock.AnythingOfType(pointerType(value)), opts...).RunAndReturn(func(f func(restatecontext.RunContext) (any, error), i any, ro ...options.RunOption) restate.TerminalError {
 		if err != nil {
 			return err
 		}
@@ -89,9 +90,17 @@ func (_e *MockContext_Expecter) RunAndExpect(ctx restatecontext.RunContext, expe
 	})
 }
 
-// GetAndReturn is a helper method to mock a typical 'Get' call; return a concrete value, or no value if nil interface is provided
-func (_e *MockContext_Expecter) GetAndReturn(key interface{}, value any) *MockContext_Get_Call {
-	return _e.Get(key, mock.AnythingOfType(pointerType(value))).RunAndReturn(func(s string, i interface{}, g ...options.GetOption) (bool, restate.TerminalError) {
+// ShieldAndRun is a helper method to mock a 'Shield' call, executing the shielded function.
+func (_e *MockContext_Expecter) ShieldAndRun() *MockContext_Shield_Call {
+	return _e.Shield(mock.Anything).RunAndReturn(func(fn func()) {
+		fn()
+	})
+}
+
+// GetAndReturn is a helper method to mock a typical 'Get' call; return a concrete value, or no value if nil interface is provided.
+// The options passed to Get, if any, must be matched by opts
+func (_e *MockContext_Expecter) GetAndReturn(key interface{}, value any, opts ...interface{}) *MockContext_Get_Call {
+	return _e.Get(key, mock.AnythingOfType(pointerType(value)), opts...).RunAndReturn(func(s string, i interface{}, g ...options.GetOption) (bool, restate.TerminalError) {
 		if value == nil {
 			return false, nil
 		}
@@ -117,24 +126,27 @@ func (_e *MockContext_Expecter) WithRandSeed(seed uint64) {
 	_e.RandUUID().RunAndReturn(func() uuid.UUID { return randsource.UUIDFromRand(r) }).Maybe()
 }
 
-// MockServiceClient is a helper method to mock a typical 'Service' call on a ctx; return a mocked Client object
-func (_e *MockContext_Expecter) MockServiceClient(service, method interface{}) *MockClient_Expecter {
+// MockServiceClient is a helper method to mock a typical 'Service' call on a ctx; return a mocked Client object.
+// The options passed to Service, if any, must be matched by opts
+func (_e *MockContext_Expecter) MockServiceClient(service, method interface{}, opts ...interface{}) *MockClient_Expecter {
 	mockClient := NewMockClient(getT(_e.mock))
-	_e.Service(service, method).Once().Return(mockClient)
+	_e.Service(service, method, opts...).Once().Return(mockClient)
 	return mockClient.EXPECT()
 }
 
-// MockObjectClient is a helper method to mock a typical 'Object' call on a ctx; return a mocked Client object
-func (_e *MockContext_Expecter) MockObjectClient(service, key, method interface{}) *MockClient_Expecter {
+// MockObjectClient is a helper method to mock a typical 'Object' call on a ctx; return a mocked Client object.
+// The options passed to Object, if any, must be matched by opts
+func (_e *MockContext_Expecter) MockObjectClient(service, key, method interface{}, opts ...interface{}) *MockClient_Expecter {
 	mockClient := NewMockClient(getT(_e.mock))
-	_e.Object(service, key, method).Once().Return(mockClient)
+	_e.Object(service, key, method, opts...).Once().Return(mockClient)
 	return mockClient.EXPECT()
 }
 
-// MockWorkflowClient is a helper method to mock a typical 'Workflow' call on a ctx; return a mocked Client object
-func (_e *MockContext_Expecter) MockWorkflowClient(service, workflowID, method interface{}) *MockClient_Expecter {
+// MockWorkflowClient is a helper method to mock a typical 'Workflow' call on a ctx; return a mocked Client object.
+// The options passed to Workflow, if any, must be matched by opts
+func (_e *MockContext_Expecter) MockWorkflowClient(service, workflowID, method interface{}, opts ...interface{}) *MockClient_Expecter {
 	mockClient := NewMockClient(getT(_e.mock))
-	_e.Workflow(service, workflowID, method).Once().Return(mockClient)
+	_e.Workflow(service, workflowID, method, opts...).Once().Return(mockClient)
 	return mockClient.EXPECT()
 }
 
@@ -0,0 +1,49 @@
+package mocks_test
+
+import (
+	"encoding/json"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+// matchJSON matches the arguments encoded in JSON as expected, for values of types the
+// tests can't build.
+func matchJSON(expected string) any {
+	return mock.MatchedBy(func(value any) bool {
+		data, err := json.Marshal(value)
+		return err == nil && string(data) == expected
+	})
+}
+
+// getJSONAndReturn mocks a 'Get' call returning the value decoded from JSON, for values of
+// types the tests can't build.
+func getJSONAndReturn(mockCtx *mocks.MockContext, key string, value string, opts ...interface{}) *mocks.MockContext_Get_Call {
+	return mockCtx.EXPECT().Get(key, mock.Anything, opts...).RunAndReturn(func(_ string, output any, _ ...options.GetOption) (bool, restate.TerminalError) {
+		if err := json.Unmarshal([]byte(value), output); err != nil {
+			panic(err)
+		}
+		return true, nil
+	})
+}
+
+// matchCode matches the terminal errors with the given code.
+func matchCode(code restate.Code) any {
+	return mock.MatchedBy(func(err error) bool {
+		terminal := restate.AsTerminalError(err)
+		return terminal != nil && terminal.Code() == code
+	})
+}
+
+// requestJSONAndReturn mocks a 'Request' call on a client returning the value decoded from
+// JSON, for outputs of types the tests can't build.
+func requestJSONAndReturn(client *mocks.MockClient_Expecter, input any, value string) *mocks.MockClient_Request_Call {
+	return client.Request(input, mock.Anything).RunAndReturn(func(_ any, output any, _ ...options.RequestOption) restate.TerminalError {
+		if err := json.Unmarshal([]byte(value), output); err != nil {
+			panic(err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+package mocks_test
+
+import (
+	"testing"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	handlers := restate.NewLockService().Handlers()
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	mockCtx.EXPECT().Key().Return("sem")
+	call := func(handler string, input string) []byte {
+		output, err := handlers[handler].Call(mockCtx, []byte(input))
+		require.NoError(t, err)
+		return output
+	}
+
+	// The first acquire sets the limit, and is granted right away
+	mockCtx.EXPECT().Get("limit", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Get("holders", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().Get("queue", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("queue", matchJSON(`[{"holder":"inv-1","awakeableId":"a1","limit":2}]`), mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().Get("holders", mock.Anything, mock.Anything).Return(false, nil).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"holder":"inv-1","awakeableId":"a1","limit":2}]`, mock.Anything).Once()
+	mockCtx.EXPECT().Get("nextLease", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().ResolveAwakeable("a1", restate.Void{}).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.LocksServiceName, "sem", "WatchHolder").
+		MockSend(restate.LockHolder{Invocation: "inv-1", LeaseID: 1})
+	mockCtx.EXPECT().Set("holders", []restate.LockHolder{{Invocation: "inv-1", LeaseID: 1}}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("nextLease", uint64(2), mock.Anything).Once()
+	mockCtx.EXPECT().Clear("queue").Once()
+	call("Acquire", `{"holder":"inv-1","awakeableId":"a1","limit":2}`)
+
+	// Without permits left, the acquire waits in the queue
+	// The handlers modify the slices they read
+	holders := func() []restate.LockHolder {
+		return []restate.LockHolder{{Invocation: "inv-1", LeaseID: 1}, {Invocation: "inv-2", LeaseID: 2}}
+	}
+	mockCtx.EXPECT().GetAndReturn("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("holders", holders(), mock.Anything).Once()
+	mockCtx.EXPECT().Get("queue", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("queue", matchJSON(`[{"holder":"inv-3","awakeableId":"a3","limit":2,"leaseTimeout":60000000000}]`), mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("holders", holders(), mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"holder":"inv-3","awakeableId":"a3","limit":2,"leaseTimeout":60000000000}]`, mock.Anything).Once()
+	call("Acquire", `{"holder":"inv-3","awakeableId":"a3","limit":2,"leaseTimeout":60000000000}`)
+
+	// A stale lease does not release the permit
+	mockCtx.EXPECT().GetAndReturn("holders", holders(), mock.Anything).Once()
+	call("Release", `{"holder":"inv-2","leaseId":1}`)
+
+	// Releasing a permit grants it to the next waiter, whose lease expires with a delayed release
+	mockCtx.EXPECT().GetAndReturn("holders", holders(), mock.Anything).Once()
+	mockCtx.EXPECT().Set("holders", holders()[:1], mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("holders", holders()[:1], mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"holder":"inv-3","awakeableId":"a3","limit":2,"leaseTimeout":60000000000}]`, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("nextLease", uint64(3), mock.Anything).Once()
+	mockCtx.EXPECT().ResolveAwakeable("a3", restate.Void{}).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.LocksServiceName, "sem", "WatchHolder").
+		MockSend(restate.LockHolder{Invocation: "inv-3", LeaseID: 3})
+	mockCtx.EXPECT().MockObjectClient(restate.LocksServiceName, "sem", "Release").
+		MockSend(matchJSON(`{"holder":"inv-3","leaseId":3}`), restate.WithDelay(time.Minute))
+	mockCtx.EXPECT().Set("holders", []restate.LockHolder{{Invocation: "inv-1", LeaseID: 1}, {Invocation: "inv-3", LeaseID: 3}}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("nextLease", uint64(4), mock.Anything).Once()
+	mockCtx.EXPECT().Clear("queue").Once()
+	call("Release", `{"holder":"inv-2","leaseId":2}`)
+
+	mockCtx.EXPECT().GetAndReturn("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("holders", holders(), mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"holder":"inv-3","awakeableId":"a3","limit":2}]`, mock.Anything).Once()
+	require.JSONEq(t, `{
+		"limit": 2,
+		"holders": [{"invocation":"inv-1","leaseId":1},{"invocation":"inv-2","leaseId":2}],
+		"waiting": ["inv-3"]
+	}`, string(call("Status", `null`)))
+}
+
+func TestSemaphoreRejections(t *testing.T) {
+	handlers := restate.NewLockService().Handlers()
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	mockCtx.EXPECT().Key().Return("sem")
+	call := func(input string) {
+		_, err := handlers["Acquire"].Call(mockCtx, []byte(input))
+		require.NoError(t, err)
+	}
+
+	mockCtx.EXPECT().RejectAwakeable("a1", matchCode(400)).Once()
+	call(`{"holder":"inv-1","awakeableId":"a1","limit":0}`)
+
+	// The limit is set by the first acquire
+	mockCtx.EXPECT().GetAndReturn("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().RejectAwakeable("a2", matchCode(409)).Once()
+	call(`{"holder":"inv-2","awakeableId":"a2","limit":3}`)
+
+	// Acquiring a permit already held fails
+	mockCtx.EXPECT().GetAndReturn("limit", 2, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("holders", []restate.LockHolder{{Invocation: "inv-1", LeaseID: 1}}, mock.Anything).Once()
+	mockCtx.EXPECT().RejectAwakeable("a3", matchCode(409)).Once()
+	call(`{"holder":"inv-1","awakeableId":"a3","limit":2}`)
+}
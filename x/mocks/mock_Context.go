@@ -756,6 +756,39 @@ func (_c *MockContext_Object_Call) RunAndReturn(run func(string, string, string,
 	return _c
 }
 
+// OnCancel provides a mock function with given fields: fn
+func (_m *MockContext) OnCancel(fn func()) {
+	_m.Called(fn)
+}
+
+// MockContext_OnCancel_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OnCancel'
+type MockContext_OnCancel_Call struct {
+	*mock.Call
+}
+
+// OnCancel is a helper method to define mock.On call
+//   - fn func()
+func (_e *MockContext_Expecter) OnCancel(fn interface{}) *MockContext_OnCancel_Call {
+	return &MockContext_OnCancel_Call{Call: _e.mock.On("OnCancel", fn)}
+}
+
+func (_c *MockContext_OnCancel_Call) Run(run func(fn func())) *MockContext_OnCancel_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(func()))
+	})
+	return _c
+}
+
+func (_c *MockContext_OnCancel_Call) Return() *MockContext_OnCancel_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockContext_OnCancel_Call) RunAndReturn(run func(func())) *MockContext_OnCancel_Call {
+	_c.Run(run)
+	return _c
+}
+
 // Promise provides a mock function with given fields: name, _a1
 func (_m *MockContext) Promise(name string, _a1 ...options.PromiseOption) restatecontext.DurablePromise {
 	_va := make([]interface{}, len(_a1))
@@ -1415,6 +1448,39 @@ func (_c *MockContext_Set_Call) RunAndReturn(run func(string, any, ...options.Se
 	return _c
 }
 
+// Shield provides a mock function with given fields: fn
+func (_m *MockContext) Shield(fn func()) {
+	_m.Called(fn)
+}
+
+// MockContext_Shield_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Shield'
+type MockContext_Shield_Call struct {
+	*mock.Call
+}
+
+// Shield is a helper method to define mock.On call
+//   - fn func()
+func (_e *MockContext_Expecter) Shield(fn interface{}) *MockContext_Shield_Call {
+	return &MockContext_Shield_Call{Call: _e.mock.On("Shield", fn)}
+}
+
+func (_c *MockContext_Shield_Call) Run(run func(fn func())) *MockContext_Shield_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(func()))
+	})
+	return _c
+}
+
+func (_c *MockContext_Shield_Call) Return() *MockContext_Shield_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockContext_Shield_Call) RunAndReturn(run func(func())) *MockContext_Shield_Call {
+	_c.Run(run)
+	return _c
+}
+
 // Signal provides a mock function with given fields: name, _a1
 func (_m *MockContext) Signal(name string, _a1 ...options.SignalOption) restatecontext.SignalFuture {
 	_va := make([]interface{}, len(_a1))
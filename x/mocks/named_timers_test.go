@@ -0,0 +1,108 @@
+package mocks_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamedTimers(t *testing.T) {
+	handlers := restate.EnableTimers(restate.NewObject("Object", restate.WithValidator(restate.StructTagValidator))).Handlers()
+	require.NotNil(t, handlers[restate.FireTimerHandlerName].GetOptions().Validator)
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{Service: "Object"})
+	mockCtx.EXPECT().Key().Return("key")
+	ctx := restate.WithMockContext(mockCtx)
+	fire := func(input string) {
+		_, err := handlers[restate.FireTimerHandlerName].Call(mockCtx, []byte(input))
+		require.NoError(t, err)
+	}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	reminder := `{"id":"reminder","at":"2024-01-01T11:00:00Z","target":{"handler":"Remind","input":"standup"},"version":1}`
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Get("restate.timers.version", mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("restate.timers.version", uint64(1)).Once()
+	mockCtx.EXPECT().Set("restate.timer.reminder", matchJSON(reminder)).Once()
+	mockCtx.EXPECT().MockObjectClient("Object", "key", restate.FireTimerHandlerName).
+		MockSend(matchJSON(`{"id":"reminder","version":1}`), restate.WithDelay(time.Hour))
+	require.NoError(t, restate.ScheduleTimer(ctx, "reminder", start.Add(time.Hour), restate.TimerTarget{Handler: "Remind", Input: json.RawMessage(`"standup"`)}))
+
+	followup := `{"id":"followup","at":"2024-01-01T10:30:00Z","target":{"service":"Mailer","handler":"Send"},"version":2}`
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().GetAndReturn("restate.timers.version", uint64(1)).Once()
+	mockCtx.EXPECT().Set("restate.timers.version", uint64(2)).Once()
+	mockCtx.EXPECT().Set("restate.timer.followup", matchJSON(followup)).Once()
+	mockCtx.EXPECT().MockObjectClient("Object", "key", restate.FireTimerHandlerName).
+		MockSend(matchJSON(`{"id":"followup","version":2}`), restate.WithDelay(30*time.Minute))
+	require.NoError(t, restate.ScheduleTimer(ctx, "followup", start.Add(30*time.Minute), restate.TimerTarget{Service: "Mailer", Handler: "Send"}))
+
+	require.Equal(t, restate.Code(400), restate.ScheduleTimer(ctx, "invalid", start, restate.TimerTarget{Handler: "Remind", Input: json.RawMessage(`{`)}).Code())
+
+	mockCtx.EXPECT().Keys().Return([]string{"restate.timers.version", "restate.timer.reminder", "restate.timer.followup", "other"}, nil).Once()
+	getJSONAndReturn(mockCtx, "restate.timer.reminder", reminder).Once()
+	getJSONAndReturn(mockCtx, "restate.timer.followup", followup).Once()
+	output, err := handlers[restate.ListTimersHandlerName].Call(mockCtx, []byte(`null`))
+	require.NoError(t, err)
+	var timers []restate.Timer
+	require.NoError(t, json.Unmarshal(output, &timers))
+	require.Equal(t, []string{"followup", "reminder"}, []string{timers[0].ID, timers[1].ID})
+
+	// Snoozing the reminder makes its first firing stale
+	snoozed := `{"id":"reminder","at":"2024-01-01T12:00:00Z","target":{"handler":"Remind","input":"standup"},"version":3}`
+	getJSONAndReturn(mockCtx, "restate.timer.reminder", reminder).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().GetAndReturn("restate.timers.version", uint64(2)).Once()
+	mockCtx.EXPECT().Set("restate.timers.version", uint64(3)).Once()
+	mockCtx.EXPECT().Set("restate.timer.reminder", matchJSON(snoozed)).Once()
+	mockCtx.EXPECT().MockObjectClient("Object", "key", restate.FireTimerHandlerName).
+		MockSend(matchJSON(`{"id":"reminder","version":3}`), restate.WithDelay(2*time.Hour))
+	require.NoError(t, restate.Reschedule(ctx, "reminder", start.Add(2*time.Hour)))
+
+	getJSONAndReturn(mockCtx, "restate.timer.reminder", snoozed).Once()
+	fire(`{"id":"reminder","version":1}`)
+
+	// Timers without a target service invoke the same key
+	getJSONAndReturn(mockCtx, "restate.timer.reminder", snoozed).Once()
+	mockCtx.EXPECT().Clear("restate.timer.reminder").Once()
+	mockCtx.EXPECT().MockObjectClient("Object", "key", "Remind", restate.WithBinary).
+		MockSend([]byte(`"standup"`))
+	fire(`{"id":"reminder","version":3}`)
+
+	// Fired timers are gone
+	mockCtx.EXPECT().Get("restate.timer.reminder", mock.Anything).Return(false, nil).Once()
+	require.Equal(t, restate.Code(404), restate.Reschedule(ctx, "reminder", start).Code())
+
+	getJSONAndReturn(mockCtx, "restate.timer.followup", followup).Once()
+	mockCtx.EXPECT().Clear("restate.timer.followup").Once()
+	cancelled, err := restate.CancelTimer(ctx, "followup")
+	require.NoError(t, err)
+	require.True(t, cancelled)
+
+	mockCtx.EXPECT().Get("restate.timer.followup", mock.Anything).Return(false, nil).Once()
+	fire(`{"id":"followup","version":2}`)
+
+	// Scheduling the same timer again does not revive stale firings
+	rescheduled := `{"id":"followup","at":"2024-01-01T10:01:00Z","target":{"service":"Mailer","handler":"Send"},"version":4}`
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().GetAndReturn("restate.timers.version", uint64(3)).Once()
+	mockCtx.EXPECT().Set("restate.timers.version", uint64(4)).Once()
+	mockCtx.EXPECT().Set("restate.timer.followup", matchJSON(rescheduled)).Once()
+	mockCtx.EXPECT().MockObjectClient("Object", "key", restate.FireTimerHandlerName).
+		MockSend(matchJSON(`{"id":"followup","version":4}`), restate.WithDelay(time.Minute))
+	require.NoError(t, restate.ScheduleTimer(ctx, "followup", start.Add(time.Minute), restate.TimerTarget{Service: "Mailer", Handler: "Send"}))
+
+	getJSONAndReturn(mockCtx, "restate.timer.followup", rescheduled).Once()
+	fire(`{"id":"followup","version":2}`)
+
+	getJSONAndReturn(mockCtx, "restate.timer.followup", rescheduled).Once()
+	mockCtx.EXPECT().Clear("restate.timer.followup").Once()
+	mockCtx.EXPECT().MockServiceClient("Mailer", "Send", restate.WithBinary).
+		MockSend([]byte(nil))
+	fire(`{"id":"followup","version":4}`)
+}
@@ -0,0 +1,81 @@
+package mocks_test
+
+import (
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/internal/stringmap"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+type salutation struct {
+	Name string `json:"name"`
+}
+
+func TestAcceptedCodecs(t *testing.T) {
+	handler := restate.NewServiceHandler(func(ctx restate.Context, input salutation) (salutation, error) {
+		return salutation{Name: "Hello " + input.Name}, nil
+	}, restate.WithAcceptedCodecs(encoding.CBORCodec, encoding.MsgPackCodec))
+	restate.NewService("Greeter").Handler("Greet", handler)
+
+	call := func(headers map[string]string, input []byte) ([]byte, error) {
+		mockCtx := mocks.NewMockContext(t)
+		mockCtx.EXPECT().Request().Return(&restate.Request{Headers: stringmap.New(headers)})
+		return handler.Call(mockCtx, input)
+	}
+	cbor := func(v any) []byte {
+		data, err := encoding.CBORCodec.Marshal(v)
+		require.NoError(t, err)
+		return data
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		output, err := call(nil, []byte(`{"name":"Ada"}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name":"Hello Ada"}`, string(output))
+	})
+
+	t.Run("content type", func(t *testing.T) {
+		output, err := call(map[string]string{"content-type": "application/cbor"}, cbor(salutation{Name: "Ada"}))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name":"Hello Ada"}`, string(output))
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		output, err := call(map[string]string{
+			"Content-Type": "application/json; charset=utf-8",
+			"Accept":       "application/json;q=0.5, application/cbor, */*;q=0.1",
+		}, []byte(`{"name":"Ada"}`))
+		require.NoError(t, err)
+		require.Equal(t, cbor(salutation{Name: "Hello Ada"}), output)
+
+		output, err = call(map[string]string{"accept": "application/x-msgpack, application/*"}, []byte(`{"name":"Ada"}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name":"Hello Ada"}`, string(output))
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		_, err := call(map[string]string{"content-type": "application/proto"}, nil)
+		require.Equal(t, restate.Code(415), restate.AsTerminalError(err).Code())
+		require.EqualError(t, err, "unsupported content type application/proto, expected one of application/json, application/cbor, application/msgpack")
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		_, err := call(map[string]string{"accept": "text/plain, application/json;q=0"}, []byte(`{"name":"Ada"}`))
+		require.Equal(t, restate.Code(406), restate.AsTerminalError(err).Code())
+	})
+
+	t.Run("discovery", func(t *testing.T) {
+		payload := handler.InputPayload()
+		require.Equal(t, "application/json, application/cbor, application/msgpack", *payload.ContentType)
+		require.NotNil(t, payload.JsonSchema)
+
+		single := restate.NewServiceHandler(func(ctx restate.Context, input salutation) (salutation, error) {
+			return input, nil
+		}, restate.WithAcceptedCodecs(encoding.ProtoJSONCodec))
+		restate.NewService("Single").Handler("Greet", single)
+		require.Equal(t, "application/json", *single.InputPayload().ContentType)
+	})
+}
@@ -0,0 +1,174 @@
+package mocks_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	handlers := restate.NewRateLimiterService().Handlers()
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	mockCtx.EXPECT().Key().Return("api").Maybe()
+	call := func(handler string, input string) ([]byte, error) {
+		return handlers[handler].Call(mockCtx, []byte(input))
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	config := &restate.RateLimitConfig{Policy: restate.RateLimitTokenBucket, Rate: 1, Burst: 2}
+
+	// Unconfigured limiters reject requests
+	mockCtx.EXPECT().Get("config", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().RejectAwakeable("a0", matchCode(404)).Once()
+	_, err := call("Acquire", `{"awakeableId":"a0","cost":1}`)
+	require.NoError(t, err)
+
+	_, err = call("Configure", `{"rate":-1,"burst":2}`)
+	require.Equal(t, restate.Code(400), restate.AsTerminalError(err).Code())
+
+	mockCtx.EXPECT().Get("config", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("config", config, mock.Anything).Once()
+	_, err = call("Configure", `{"rate":1,"burst":2}`)
+	require.NoError(t, err)
+
+	// A new bucket is full
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	mockCtx.EXPECT().Get("bucket", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Get("queue", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().ResolveAwakeable("a1", restate.Void{}).Once()
+	mockCtx.EXPECT().Set("bucket", matchJSON(`{"tokens":1,"updatedAt":"2024-01-01T00:00:00Z"}`), mock.Anything).Once()
+	_, err = call("Acquire", `{"awakeableId":"a1","cost":1}`)
+	require.NoError(t, err)
+
+	// Once the bucket is empty, requests wait for a refill
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z"}`, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Get("queue", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("queue", matchJSON(`[{"awakeableId":"a2","cost":1}]`), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.RateLimiterServiceName, "api", "Wake").
+		MockSend(restate.Void{}, restate.WithDelay(time.Second))
+	mockCtx.EXPECT().Set("bucket", matchJSON(`{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","wakeAt":"2024-01-01T00:00:01Z"}`), mock.Anything).Once()
+	_, err = call("Acquire", `{"awakeableId":"a2","cost":1}`)
+	require.NoError(t, err)
+
+	// A wake up is already scheduled for the head of the queue
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","wakeAt":"2024-01-01T00:00:01Z"}`, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"awakeableId":"a2","cost":1}]`, mock.Anything).Once()
+	mockCtx.EXPECT().Set("queue", matchJSON(`[{"awakeableId":"a2","cost":1},{"awakeableId":"a3","cost":1}]`), mock.Anything).Once()
+	mockCtx.EXPECT().Set("bucket", matchJSON(`{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","wakeAt":"2024-01-01T00:00:01Z"}`), mock.Anything).Once()
+	_, err = call("Acquire", `{"awakeableId":"a3","cost":1}`)
+	require.NoError(t, err)
+
+	// Rejected requests leave the state unchanged
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","wakeAt":"2024-01-01T00:00:01Z"}`, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().RejectAwakeable("a4", matchCode(400)).Once()
+	_, err = call("Acquire", `{"awakeableId":"a4","cost":3}`)
+	require.NoError(t, err)
+
+	// One token was refilled
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"awakeableId":"a2","cost":1},{"awakeableId":"a3","cost":1}]`, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","wakeAt":"2024-01-01T00:00:01Z"}`, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start.Add(time.Second), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().ResolveAwakeable("a2", restate.Void{}).Once()
+	mockCtx.EXPECT().Set("queue", matchJSON(`[{"awakeableId":"a3","cost":1}]`), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.RateLimiterServiceName, "api", "Wake").
+		MockSend(restate.Void{}, restate.WithDelay(time.Second))
+	mockCtx.EXPECT().Set("bucket", matchJSON(`{"tokens":0,"updatedAt":"2024-01-01T00:00:01Z","wakeAt":"2024-01-01T00:00:02Z"}`), mock.Anything).Once()
+	_, err = call("Wake", `null`)
+	require.NoError(t, err)
+
+	// Raising the rate lets the remaining waiter in sooner
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":0,"updatedAt":"2024-01-01T00:00:01Z","wakeAt":"2024-01-01T00:00:02Z"}`, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start.Add(1500*time.Millisecond), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Set("config", &restate.RateLimitConfig{Policy: restate.RateLimitTokenBucket, Rate: 10, Burst: 2}, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"awakeableId":"a3","cost":1}]`, mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.RateLimiterServiceName, "api", "Wake").
+		MockSend(restate.Void{}, restate.WithDelay(50*time.Millisecond))
+	mockCtx.EXPECT().Set("bucket", matchJSON(`{"tokens":0.5,"updatedAt":"2024-01-01T00:00:01.5Z","wakeAt":"2024-01-01T00:00:01.55Z"}`), mock.Anything).Once()
+	_, err = call("Configure", `{"rate":10,"burst":2}`)
+	require.NoError(t, err)
+
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":1.5,"updatedAt":"2024-01-01T00:00:01Z"}`, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "queue", `[{"awakeableId":"a3","cost":1}]`, mock.Anything).Once()
+	output, err := call("Status", `null`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"config":{"policy":"token_bucket","rate":1,"burst":2},"tokens":1.5,"waiting":1}`, string(output))
+}
+
+func TestRateLimiterStateErrors(t *testing.T) {
+	handlers := restate.NewRateLimiterService().Handlers()
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	config := &restate.RateLimitConfig{Policy: restate.RateLimitTokenBucket, Rate: 1, Burst: 2}
+
+	// The error is returned, instead of rejecting the request
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	mockCtx.EXPECT().Get("bucket", mock.Anything, mock.Anything).Return(false, restate.ToTerminalError(errors.New("unavailable"), restate.WithErrorCode(503))).Once()
+	_, err := handlers["Acquire"].Call(mockCtx, []byte(`{"awakeableId":"a1","cost":1}`))
+	require.Equal(t, restate.Code(503), restate.AsTerminalError(err).Code())
+
+	// The bucket is not saved when the handler fails
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	mockCtx.EXPECT().Get("bucket", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().RunAndReturn(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Get("queue", mock.Anything, mock.Anything).Return(false, restate.ToTerminalError(errors.New("unavailable"), restate.WithErrorCode(503))).Once()
+	_, err = handlers["Acquire"].Call(mockCtx, []byte(`{"awakeableId":"a1","cost":1}`))
+	require.Equal(t, restate.Code(503), restate.AsTerminalError(err).Code())
+}
+
+func TestRateLimiterLeakyBucket(t *testing.T) {
+	handlers := restate.NewRateLimiterService().Handlers()
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	mockCtx.EXPECT().Key().Return("api").Maybe()
+	call := func(handler string, input string) []byte {
+		output, err := handlers[handler].Call(mockCtx, []byte(input))
+		require.NoError(t, err)
+		return output
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	config := &restate.RateLimitConfig{Policy: restate.RateLimitLeakyBucket, Rate: 1}
+
+	mockCtx.EXPECT().Get("config", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("config", config, mock.Anything).Once()
+	call("Configure", `{"policy":"leaky_bucket","rate":1}`)
+
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	mockCtx.EXPECT().Get("bucket", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().ResolveAwakeable("a1", restate.Void{}).Once()
+	mockCtx.EXPECT().Set("bucket", matchJSON(`{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","nextFree":"2024-01-01T00:00:01Z"}`), mock.Anything).Once()
+	call("Acquire", `{"awakeableId":"a1","cost":1}`)
+
+	// Requests are spaced by their cost
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","nextFree":"2024-01-01T00:00:01Z"}`, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.RateLimiterServiceName, "api", "Grant").
+		MockSend(matchJSON(`{"awakeableId":"a2","cost":2}`), restate.WithDelay(time.Second))
+	mockCtx.EXPECT().Set("bucket", matchJSON(`{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","nextFree":"2024-01-01T00:00:03Z"}`), mock.Anything).Once()
+	call("Acquire", `{"awakeableId":"a2","cost":2}`)
+
+	mockCtx.EXPECT().GetAndReturn("config", config, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "bucket", `{"tokens":0,"updatedAt":"2024-01-01T00:00:00Z","nextFree":"2024-01-01T00:00:03Z"}`, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Get("queue", mock.Anything, mock.Anything).Return(false, nil).Once()
+	require.JSONEq(t, `false`, string(call("TryAcquire", `{"cost":1}`)))
+
+	mockCtx.EXPECT().ResolveAwakeable("a2", restate.Void{}).Once()
+	call("Grant", `{"awakeableId":"a2","cost":2}`)
+}
@@ -0,0 +1,139 @@
+package mocks_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// jobHeaders are the headers sent with the run of the job at the given time.
+func jobHeaders(id string, at time.Time) any {
+	return restate.WithHeaders(map[string]string{
+		restate.JobIDHeader:          id,
+		restate.JobScheduledAtHeader: at.Format(time.RFC3339),
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	handlers := restate.NewSchedulerService().Handlers()
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	mockCtx.EXPECT().Key().Return("report")
+	call := func(handler string, input string) []byte {
+		output, err := handlers[handler].Call(mockCtx, []byte(input))
+		require.NoError(t, err)
+		return output
+	}
+	start := time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC)
+
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	_, err := handlers["Schedule"].Call(mockCtx, []byte(`{"cron":"0 61 * * *","target":{"service":"Reports","handler":"Generate"}}`))
+	require.Equal(t, restate.Code(400), restate.AsTerminalError(err).Code())
+
+	job := `{"cron":"0 * * * *","target":{"service":"Reports","handler":"Generate"},"input":{"full":true},"missedRuns":"skip"}`
+	scheduled := `{"job":` + job + `,"paused":false,"nextRunAt":"2024-01-01T11:00:00Z","runs":0,"anchor":"2024-01-01T10:05:00Z","generation":1}`
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Get("job", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "", "Index").
+		MockSend(matchJSON(`{"id":"report","add":true}`))
+	mockCtx.EXPECT().Set("job", matchJSON(scheduled), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "report", "Run").
+		MockSend(matchJSON(`{"generation":1,"at":"2024-01-01T11:00:00Z"}`), restate.WithDelay(55*time.Minute))
+	call("Schedule", `{"cron":"0 * * * *","target":{"service":"Reports","handler":"Generate"},"input":{"full":true}}`)
+
+	getJSONAndReturn(mockCtx, "job", scheduled, mock.Anything).Once()
+	var status restate.JobStatus
+	require.NoError(t, json.Unmarshal(call("Get", `null`), &status))
+	require.Equal(t, start.Add(55*time.Minute), status.NextRunAt)
+
+	// Running invokes the target and schedules the next run
+	ran := `{"job":` + job + `,"paused":false,"nextRunAt":"2024-01-01T12:00:00Z","lastRunAt":"2024-01-01T11:00:00Z","runs":1,"anchor":"2024-01-01T10:05:00Z","generation":2}`
+	getJSONAndReturn(mockCtx, "job", scheduled, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start.Add(55*time.Minute), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().MockServiceClient("Reports", "Generate", restate.WithBinary).
+		MockSend([]byte(`{"full":true}`), jobHeaders("report", start.Add(55*time.Minute)))
+	mockCtx.EXPECT().Set("job", matchJSON(ran), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "report", "Run").
+		MockSend(matchJSON(`{"generation":2,"at":"2024-01-01T12:00:00Z"}`), restate.WithDelay(time.Hour))
+	call("Run", `{"generation":1,"at":"2024-01-01T11:00:00Z"}`)
+
+	// Pausing supersedes the pending run
+	paused := `{"job":` + job + `,"paused":true,"nextRunAt":"2024-01-01T12:00:00Z","lastRunAt":"2024-01-01T11:00:00Z","runs":1,"anchor":"2024-01-01T10:05:00Z","generation":3}`
+	getJSONAndReturn(mockCtx, "job", ran, mock.Anything).Once()
+	mockCtx.EXPECT().Set("job", matchJSON(paused), mock.Anything).Once()
+	call("Pause", `null`)
+
+	getJSONAndReturn(mockCtx, "job", paused, mock.Anything).Once()
+	call("Run", `{"generation":2,"at":"2024-01-01T12:00:00Z"}`)
+
+	// Resuming 2 hours later skips the missed runs
+	resumed := `{"job":` + job + `,"paused":false,"nextRunAt":"2024-01-01T14:00:00Z","lastRunAt":"2024-01-01T11:00:00Z","runs":1,"anchor":"2024-01-01T10:05:00Z","generation":4}`
+	getJSONAndReturn(mockCtx, "job", paused, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start.Add(3*time.Hour+50*time.Minute), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Set("job", matchJSON(resumed), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "report", "Run").
+		MockSend(matchJSON(`{"generation":4,"at":"2024-01-01T14:00:00Z"}`), restate.WithDelay(5*time.Minute))
+	call("Resume", `null`)
+
+	// Catching up instead runs once per missed hour
+	catchUp := `{"cron":"0 * * * *","target":{"service":"Reports","key":"eu","handler":"Generate"},"missedRuns":"catch_up"}`
+	late := `{"job":` + catchUp + `,"paused":false,"nextRunAt":"2024-01-01T14:00:00Z","lastRunAt":"2024-01-01T11:00:00Z","runs":1,"anchor":"2024-01-01T10:05:00Z","generation":5}`
+	caughtUp := `{"job":` + catchUp + `,"paused":false,"nextRunAt":"2024-01-01T17:00:00Z","lastRunAt":"2024-01-01T16:00:00Z","runs":4,"anchor":"2024-01-01T10:05:00Z","generation":6}`
+	getJSONAndReturn(mockCtx, "job", late, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start.Add(6*time.Hour+45*time.Minute), nil, restate.WithName("now")).Once()
+	for hour := 14; hour <= 16; hour++ {
+		mockCtx.EXPECT().MockObjectClient("Reports", "eu", "Generate", restate.WithBinary).
+			MockSend([]byte(nil), jobHeaders("report", time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)))
+	}
+	mockCtx.EXPECT().Set("job", matchJSON(caughtUp), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "report", "Run").
+		MockSend(matchJSON(`{"generation":6,"at":"2024-01-01T17:00:00Z"}`), restate.WithDelay(10*time.Minute))
+	call("Run", `{"generation":5,"at":"2024-01-01T14:00:00Z"}`)
+
+	getJSONAndReturn(mockCtx, "job", caughtUp, mock.Anything).Once()
+	mockCtx.EXPECT().Clear("job").Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "", "Index").
+		MockSend(matchJSON(`{"id":"report"}`))
+	call("Delete", `null`)
+
+	mockCtx.EXPECT().Get("job", mock.Anything, mock.Anything).Return(false, nil).Once()
+	_, err = handlers["Get"].Call(mockCtx, []byte(`null`))
+	require.Equal(t, restate.Code(404), restate.AsTerminalError(err).Code())
+}
+
+func TestSchedulerInterval(t *testing.T) {
+	handlers := restate.NewSchedulerService().Handlers()
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	mockCtx.EXPECT().Key().Return("cleanup")
+	start := time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC)
+
+	job := `{"every":"90m","target":{"service":"Cleanup","handler":"Run"},"missedRuns":"skip"}`
+	scheduled := `{"job":` + job + `,"paused":false,"nextRunAt":"2024-01-01T11:35:00Z","runs":0,"anchor":"2024-01-01T10:05:00Z","generation":1}`
+	mockCtx.EXPECT().RunAndReturn(start, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Get("job", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "", "Index").
+		MockSend(matchJSON(`{"id":"cleanup","add":true}`))
+	mockCtx.EXPECT().Set("job", matchJSON(scheduled), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "cleanup", "Run").
+		MockSend(matchJSON(`{"generation":1,"at":"2024-01-01T11:35:00Z"}`), restate.WithDelay(90*time.Minute))
+	_, err := handlers["Schedule"].Call(mockCtx, []byte(`{"every":"90m","target":{"service":"Cleanup","handler":"Run"}}`))
+	require.NoError(t, err)
+
+	// A late run is only performed once
+	ran := `{"job":` + job + `,"paused":false,"nextRunAt":"2024-01-01T14:35:00Z","lastRunAt":"2024-01-01T11:35:00Z","runs":1,"anchor":"2024-01-01T10:05:00Z","generation":2}`
+	getJSONAndReturn(mockCtx, "job", scheduled, mock.Anything).Once()
+	mockCtx.EXPECT().RunAndReturn(start.Add(4*time.Hour), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().MockServiceClient("Cleanup", "Run", restate.WithBinary).
+		MockSend([]byte(nil), jobHeaders("cleanup", start.Add(90*time.Minute)))
+	mockCtx.EXPECT().Set("job", matchJSON(ran), mock.Anything).Once()
+	mockCtx.EXPECT().MockObjectClient(restate.SchedulerServiceName, "cleanup", "Run").
+		MockSend(matchJSON(`{"generation":2,"at":"2024-01-01T14:35:00Z"}`), restate.WithDelay(30*time.Minute))
+	_, err = handlers["Run"].Call(mockCtx, []byte(`{"generation":1,"at":"2024-01-01T11:35:00Z"}`))
+	require.NoError(t, err)
+}
@@ -0,0 +1,139 @@
+package mocks_test
+
+import (
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateList(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+	list := restate.NewStateList[int]("list", restate.WithChunkSize(3))
+
+	// Appending fills the last chunk before adding new ones
+	mockCtx.EXPECT().Get("list", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("list/0", []int{0, 1, 2}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("list/1", []int{3, 4}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("list", matchJSON(`{"chunks":[{"id":0,"len":3},{"id":1,"len":2}],"nextId":2}`), mock.Anything).Once()
+	require.NoError(t, list.Append(ctx, 0, 1, 2, 3, 4))
+
+	getJSONAndReturn(mockCtx, "list", `{"chunks":[{"id":0,"len":3},{"id":1,"len":2}],"nextId":2}`, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("list/1", []int{3, 4}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("list/1", []int{3, 4, 5}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("list/2", []int{6}, mock.Anything).Once()
+	mockCtx.EXPECT().Set("list", matchJSON(`{"chunks":[{"id":0,"len":3},{"id":1,"len":3},{"id":2,"len":1}],"nextId":3}`), mock.Anything).Once()
+	require.NoError(t, list.Append(ctx, 5, 6))
+
+	index := `{"chunks":[{"id":0,"len":3},{"id":1,"len":3},{"id":2,"len":1}],"nextId":3}`
+	getJSONAndReturn(mockCtx, "list", index, mock.Anything).Once()
+	length, err := list.Len(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 7, length)
+
+	// Only the chunks holding the requested entries are read
+	getJSONAndReturn(mockCtx, "list", index, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("list/1", []int{3, 4, 5}, mock.Anything).Once()
+	value, err := list.Get(ctx, 4)
+	require.NoError(t, err)
+	require.Equal(t, 4, value)
+
+	getJSONAndReturn(mockCtx, "list", index, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("list/0", []int{0, 1, 2}, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("list/1", []int{3, 4, 5}, mock.Anything).Once()
+	page, err := list.Range(ctx, 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 3, 4}, page)
+
+	// Deleting the last entry of a chunk drops it
+	getJSONAndReturn(mockCtx, "list", index, mock.Anything).Once()
+	mockCtx.EXPECT().GetAndReturn("list/2", []int{6}, mock.Anything).Once()
+	mockCtx.EXPECT().Clear("list/2").Once()
+	mockCtx.EXPECT().Set("list", matchJSON(`{"chunks":[{"id":0,"len":3},{"id":1,"len":3}],"nextId":3}`), mock.Anything).Once()
+	require.NoError(t, list.Delete(ctx, 6))
+
+	getJSONAndReturn(mockCtx, "list", index, mock.Anything).Once()
+	_, err = list.Get(ctx, 7)
+	require.Error(t, err)
+	require.EqualValues(t, 404, err.Code())
+
+	mockCtx.EXPECT().Keys().Return([]string{"list", "list/0", "list/1", "other"}, nil).Once()
+	mockCtx.EXPECT().Clear("list/0").Once()
+	mockCtx.EXPECT().Clear("list/1").Once()
+	mockCtx.EXPECT().Clear("list").Once()
+	require.NoError(t, list.Clear(ctx))
+}
+
+func TestStateMap(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+	m := restate.NewStateMap[string, int]("map")
+
+	mockCtx.EXPECT().Get(`map/"a"`, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set(`map/"a"`, 1, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "map", `{"len":1}`, mock.Anything).Once()
+	mockCtx.EXPECT().Set("map", matchJSON(`{"len":2}`), mock.Anything).Once()
+	require.NoError(t, m.Put(ctx, "a", 1))
+
+	// Replacing an entry doesn't change the length
+	mockCtx.EXPECT().Get(`map/"a"`, mock.Anything).Return(true, nil).Once()
+	mockCtx.EXPECT().Set(`map/"a"`, 3, mock.Anything).Once()
+	require.NoError(t, m.Put(ctx, "a", 3))
+
+	getJSONAndReturn(mockCtx, "map", `{"len":2}`, mock.Anything).Once()
+	length, err := m.Len(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, length)
+
+	mockCtx.EXPECT().GetAndReturn(`map/"a"`, 3, mock.Anything).Once()
+	value, found, err := m.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 3, value)
+
+	mockCtx.EXPECT().Keys().Return([]string{`map/"b"`, "map", `map/"a"`}, nil).Once()
+	mockCtx.EXPECT().GetAndReturn(`map/"b"`, 2, mock.Anything).Once()
+	entries, err := m.Range(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, []restate.StateMapEntry[string, int]{{Key: "b", Value: 2}}, entries)
+
+	mockCtx.EXPECT().Get(`map/"a"`, mock.Anything).Return(true, nil).Once()
+	mockCtx.EXPECT().Clear(`map/"a"`).Once()
+	getJSONAndReturn(mockCtx, "map", `{"len":2}`, mock.Anything).Once()
+	mockCtx.EXPECT().Set("map", matchJSON(`{"len":1}`), mock.Anything).Once()
+	deleted, err := m.Delete(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	mockCtx.EXPECT().Get(`map/"a"`, mock.Anything).Return(false, nil).Once()
+	deleted, err = m.Delete(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, deleted)
+}
+
+func TestStateSet(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+	set := restate.NewStateSet[int]("set")
+
+	mockCtx.EXPECT().Get("set/3", mock.Anything).Return(true, nil).Once()
+	added, err := set.Add(ctx, 3)
+	require.NoError(t, err)
+	require.False(t, added)
+
+	mockCtx.EXPECT().Get("set/1", mock.Anything).Return(false, nil).Twice()
+	mockCtx.EXPECT().Set("set/1", struct{}{}, mock.Anything).Once()
+	getJSONAndReturn(mockCtx, "set", `{"len":1}`, mock.Anything).Once()
+	mockCtx.EXPECT().Set("set", matchJSON(`{"len":2}`), mock.Anything).Once()
+	added, err = set.Add(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	mockCtx.EXPECT().Keys().Return([]string{"set", "set/3", "set/1", "settings"}, nil).Once()
+	members, err := set.Members(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 3}, members)
+}
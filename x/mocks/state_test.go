@@ -0,0 +1,89 @@
+package mocks_test
+
+import (
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateKey(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+	count := restate.NewStateKeyWithDefault("count", 10)
+
+	mockCtx.EXPECT().Get("count", mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockCtx.EXPECT().Set("count", 11, mock.Anything).Once()
+	value, err := count.Update(ctx, func(v int) int { return v + 1 })
+	require.NoError(t, err)
+	require.Equal(t, 11, value)
+
+	mockCtx.EXPECT().GetAndReturn("count", 11, mock.Anything).Once()
+	value, err = count.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 11, value)
+
+	mockCtx.EXPECT().Clear("count").Once()
+	count.Clear(ctx)
+}
+
+// label is stored as raw text by labelCodec, registered after labelKey is declared.
+type label string
+
+type labelCodec struct{}
+
+func (labelCodec) Marshal(v any) ([]byte, error) { return []byte(v.(label)), nil }
+
+func (labelCodec) Unmarshal(data []byte, v any) error {
+	*v.(*label) = label(data)
+	return nil
+}
+
+var labelKey = restate.NewStateKey[label]("label")
+
+func init() {
+	encoding.Register[label](labelCodec{})
+}
+
+func TestStateKeyRegisteredCodec(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+
+	mockCtx.EXPECT().Set("label", label("urgent"), restate.WithCodec(labelCodec{})).Once()
+	labelKey.Set(ctx, "urgent")
+
+	mockCtx.EXPECT().GetAndReturn("label", label("urgent"), restate.WithCodec(labelCodec{})).Once()
+	value, err := labelKey.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, label("urgent"), value)
+}
+
+func TestGetAndMigrate(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+	codec := encoding.Versioned(encoding.JSONCodec, 1, encoding.Migrations{
+		0: func(old []byte) ([]byte, error) {
+			return []byte(`{"count":` + string(old) + `}`), nil
+		},
+	})
+	type counter struct {
+		Count int `json:"count"`
+	}
+
+	// Written before versioning was introduced, and written back upgraded
+	mockCtx.EXPECT().GetAndReturn("counter", []byte(`42`), restate.WithBinary).Once()
+	mockCtx.EXPECT().Set("counter", counter{Count: 42}, mock.Anything).Once()
+	value, err := restate.GetAndMigrate[counter](ctx, "counter", restate.WithCodec(codec))
+	require.NoError(t, err)
+	require.Equal(t, counter{Count: 42}, value)
+
+	current, marshalErr := encoding.Marshal(codec, counter{Count: 42})
+	require.NoError(t, marshalErr)
+	mockCtx.EXPECT().GetAndReturn("counter", current, restate.WithBinary).Once()
+	value, err = restate.GetAndMigrate[counter](ctx, "counter", restate.WithCodec(codec))
+	require.NoError(t, err)
+	require.Equal(t, counter{Count: 42}, value)
+}
@@ -0,0 +1,97 @@
+package mocks_test
+
+import (
+	"testing"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/internal/options"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateTTL(t *testing.T) {
+	def := restate.EnableStateTTL(restate.NewObject("Session", restate.WithValidator(restate.StructTagValidator)).
+		Handler("Login", restate.NewObjectHandler(func(ctx restate.ObjectContext, token string) (restate.Void, error) {
+			return restate.Void{}, restate.SetWithTTL(ctx, "token", token, time.Hour, restate.WithExpiryCallback("OnExpired"))
+		})).
+		Handler("Token", restate.NewObjectSharedHandler(func(ctx restate.ObjectSharedContext, _ restate.Void) (string, error) {
+			return restate.Get[string](ctx, "token")
+		})))
+	handlers := def.Handlers()
+	require.NotNil(t, handlers[restate.StateTTLHandlerName].GetOptions().Validator)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{Service: "Session"})
+	mockCtx.EXPECT().Key().Return("user")
+
+	var stored []byte
+	mockCtx.EXPECT().RunAndReturn(now, nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Set("token", mock.Anything, restate.WithBinary).
+		Run(func(_ string, value any, _ ...options.SetOption) { stored = value.([]byte) }).Once()
+	mockCtx.EXPECT().MockObjectClient("Session", "user", restate.StateTTLHandlerName).
+		MockSend(matchJSON(`{"key":"token","expiresAt":"2026-01-01T01:00:00Z","callback":"OnExpired"}`), restate.WithDelay(time.Hour))
+	_, err := handlers["Login"].Call(mockCtx, []byte(`"secret"`))
+	require.NoError(t, err)
+
+	// The expiry is checked for the entries set with a TTL
+	mockCtx.EXPECT().GetAndReturn("token", stored, restate.WithBinary).Once()
+	mockCtx.EXPECT().RunAndReturn(now.Add(time.Minute), nil, restate.WithName("now")).Once()
+	output, err := handlers["Token"].Call(mockCtx, nil)
+	require.NoError(t, err)
+	require.Equal(t, `"secret"`, string(output))
+
+	mockCtx.EXPECT().GetAndReturn("token", stored, restate.WithBinary).Once()
+	mockCtx.EXPECT().RunAndReturn(expiresAt, nil, restate.WithName("now")).Once()
+	output, err = handlers["Token"].Call(mockCtx, nil)
+	require.NoError(t, err)
+	require.Equal(t, `""`, string(output))
+
+	// Other entries are read as usual
+	mockCtx.EXPECT().GetAndReturn("token", []byte(`"persistent"`), restate.WithBinary).Once()
+	output, err = handlers["Token"].Call(mockCtx, nil)
+	require.NoError(t, err)
+	require.Equal(t, `"persistent"`, string(output))
+
+	mockCtx.EXPECT().GetAndReturn("token", stored, restate.WithBinary).Once()
+	at, found, err := restate.ExpiresAt(restate.WithMockContext(mockCtx), "token")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.True(t, expiresAt.Equal(at))
+
+	// A stale cleanup, for an entry set again since, is ignored
+	mockCtx.EXPECT().GetAndReturn("token", stored, restate.WithBinary).Once()
+	_, err = handlers[restate.StateTTLHandlerName].Call(mockCtx, []byte(`{"key":"token","expiresAt":"2026-01-01T00:30:00Z"}`))
+	require.NoError(t, err)
+
+	mockCtx.EXPECT().GetAndReturn("token", stored, restate.WithBinary).Once()
+	mockCtx.EXPECT().Clear("token").Once()
+	mockCtx.EXPECT().MockObjectClient("Session", "user", "OnExpired").
+		MockSend(restate.ExpiredState{Key: "token", ExpiredAt: expiresAt})
+	_, err = handlers[restate.StateTTLHandlerName].Call(mockCtx, []byte(`{"key":"token","expiresAt":"2026-01-01T01:00:00Z","callback":"OnExpired"}`))
+	require.NoError(t, err)
+}
+
+func TestRemoveTTL(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{Service: "Session"})
+	mockCtx.EXPECT().Key().Return("user")
+
+	var stored []byte
+	mockCtx.EXPECT().RunAndReturn(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), nil, restate.WithName("now")).Once()
+	mockCtx.EXPECT().Set("token", mock.Anything, restate.WithBinary).
+		Run(func(_ string, value any, _ ...options.SetOption) { stored = value.([]byte) }).Once()
+	mockCtx.EXPECT().MockObjectClient("Session", "user", restate.StateTTLHandlerName).
+		MockSend(mock.Anything, restate.WithDelay(time.Minute))
+	ctx := restate.WithMockContext(mockCtx)
+	require.NoError(t, restate.SetWithTTL(ctx, "token", "secret", time.Minute))
+
+	// The value is kept, without its expiry
+	mockCtx.EXPECT().GetAndReturn("token", stored, restate.WithBinary).Once()
+	mockCtx.EXPECT().Set("token", []byte(`"secret"`), restate.WithBinary).Once()
+	require.NoError(t, restate.RemoveTTL(ctx, "token"))
+}
@@ -0,0 +1,105 @@
+package mocks_test
+
+import (
+	"errors"
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/encoding"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+type order struct {
+	ID       string     `json:"id" validate:"required"`
+	Quantity int        `json:"quantity" validate:"min=1,max=100"`
+	Currency string     `json:"currency" validate:"oneof=EUR USD"`
+	Items    []lineItem `json:"items" validate:"min=1"`
+}
+
+type lineItem struct {
+	SKU string `json:"sku" validate:"required,len=6"`
+}
+
+type transfer struct {
+	From, To string
+}
+
+func (t transfer) Validate() error {
+	if t.From == t.To {
+		return &restate.ValidationError{Violations: []restate.FieldViolation{{Field: "To", Description: "must differ from From"}}}
+	}
+	return nil
+}
+
+type greeting string
+
+func (g *greeting) Validate() error {
+	if *g == "" {
+		return errors.New("greeting must not be empty")
+	}
+	return nil
+}
+
+func TestValidateMethod(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	calls := 0
+	handler := restate.NewServiceHandler(func(ctx restate.Context, input transfer) (restate.Void, error) {
+		calls++
+		return restate.Void{}, nil
+	})
+	restate.NewService("Bank").Handler("Transfer", handler)
+
+	_, err := handler.Call(mockCtx, []byte(`{"From":"a","To":"a"}`))
+	require.Equal(t, restate.Code(400), restate.AsTerminalError(err).Code())
+	require.Equal(t, []restate.FieldViolation{{Field: "To", Description: "must differ from From"}}, restate.FieldViolations(err))
+	require.Zero(t, calls)
+
+	_, err = handler.Call(mockCtx, []byte(`{"From":"a","To":"b"}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+
+	// Pointer receivers and plain errors
+	greet := restate.NewServiceHandler(func(ctx restate.Context, input greeting) (string, error) {
+		return "hello " + string(input), nil
+	})
+	restate.NewService("Greeter").Handler("Greet", greet)
+	_, err = greet.Call(mockCtx, []byte(`""`))
+	require.Equal(t, restate.Code(400), restate.AsTerminalError(err).Code())
+	require.Equal(t, []restate.FieldViolation{{Description: "greeting must not be empty"}}, restate.FieldViolations(err))
+}
+
+func TestStrictInput(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	handler := restate.NewServiceHandler(func(ctx restate.Context, input transfer) (restate.Void, error) {
+		return restate.Void{}, nil
+	}, restate.WithInputCodec(encoding.StrictJSONCodec))
+	restate.NewService("Strict").Handler("Transfer", handler)
+
+	_, err := handler.Call(mockCtx, []byte(`{"From":"a","To":"b","Amount":3}`))
+	require.Equal(t, restate.Code(400), restate.AsTerminalError(err).Code())
+	require.ErrorContains(t, err, `invalid input: json: unknown field "Amount"`)
+}
+
+func TestStructTagValidator(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+	handler := restate.NewObjectHandler(func(ctx restate.ObjectContext, input *order) (restate.Void, error) {
+		return restate.Void{}, nil
+	})
+	restate.NewObject("Orders", restate.WithValidator(restate.StructTagValidator)).Handler("Place", handler)
+
+	_, err := handler.Call(mockCtx, []byte(`{"quantity":0,"currency":"GBP","items":[{"sku":"abc"}]}`))
+	require.Equal(t, restate.Code(400), restate.AsTerminalError(err).Code())
+	require.Equal(t, []restate.FieldViolation{
+		{Field: "id", Description: "is required"},
+		{Field: "quantity", Description: "must be of at least 1"},
+		{Field: "currency", Description: "must be one of EUR, USD"},
+		{Field: "items[0].sku", Description: "must have a length of exactly 6"},
+	}, restate.FieldViolations(err))
+
+	_, err = handler.Call(mockCtx, []byte(`{"id":"o1","quantity":2,"currency":"EUR","items":[{"sku":"abcdef"}]}`))
+	require.NoError(t, err)
+}
@@ -0,0 +1,110 @@
+package mocks_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	restate "github.com/restatedev/sdk-go"
+	"github.com/restatedev/sdk-go/x/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartWorkflow(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-1", "Run").
+		MockSend("alice").EXPECT().GetInvocationId().Return("inv_1")
+	handle := restate.StartWorkflow[string, int](ctx, "Signup", "user-1", "alice")
+	require.Equal(t, "Signup", handle.Service())
+	require.Equal(t, "user-1", handle.WorkflowID())
+	require.Equal(t, "inv_1", handle.GetInvocationId())
+
+	attached := mocks.NewMockAttachFuture(t)
+	mockCtx.EXPECT().AttachInvocation("inv_1").Return(attached).Twice()
+	attached.EXPECT().ResponseAndReturn(42, nil).Twice()
+	output, err := handle.Attach().Response()
+	require.NoError(t, err)
+	require.Equal(t, 42, output)
+	output, err = handle.Output()
+	require.NoError(t, err)
+	require.Equal(t, 42, output)
+
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-2", "Run").
+		MockSend("bob").EXPECT().GetInvocationId().Return("inv_2")
+	failed := mocks.NewMockAttachFuture(t)
+	mockCtx.EXPECT().AttachInvocation("inv_2").Return(failed).Once()
+	failed.EXPECT().ResponseAndReturn(0, restate.ToTerminalError(fmt.Errorf("rejected"), restate.WithErrorCode(403))).Once()
+	_, err = restate.StartWorkflow[string, int](ctx, "Signup", "user-2", "bob").Output()
+	require.Equal(t, restate.Code(403), err.Code())
+}
+
+func TestWorkflowHandlePromises(t *testing.T) {
+	mockCtx := mocks.NewMockContext(t)
+	ctx := restate.WithMockContext(mockCtx)
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-1", "Run").
+		MockSend("alice").EXPECT().GetInvocationId().Return("inv_1").Maybe()
+	handle := restate.StartWorkflow[string, restate.Void](ctx, "Signup", "user-1", "alice")
+
+	email := base64.StdEncoding.EncodeToString([]byte(`{"email":"alice@example.com"}`))
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-1", restate.ResolvePromiseHandlerName).
+		Request(matchJSON(`{"name":"verified","value":"`+email+`"}`), mock.Anything).Return(nil).Once()
+	require.NoError(t, handle.ResolvePromise("verified", map[string]string{"email": "alice@example.com"}))
+
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-1", restate.ResolvePromiseHandlerName).
+		Request(mock.Anything, mock.Anything).Return(restate.ToTerminalError(fmt.Errorf("promise already completed"), restate.WithErrorCode(409))).Once()
+	require.Equal(t, restate.Code(409), handle.ResolvePromise("verified", true).Code())
+
+	// The value is encoded with the codec of the options, to be read with the same codec
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-1", restate.ResolvePromiseHandlerName).
+		Request(matchJSON(`{"name":"avatar","value":"/9g="}`), mock.Anything).Return(nil).Once()
+	require.NoError(t, handle.ResolvePromise("avatar", []byte{0xff, 0xd8}, restate.WithBinary))
+
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-1", restate.RejectPromiseHandlerName).
+		Request(matchJSON(`{"name":"approved","code":403,"message":"denied"}`), mock.Anything).Return(nil).Once()
+	require.NoError(t, handle.RejectPromise("approved", restate.ToTerminalError(fmt.Errorf("denied"), restate.WithErrorCode(403))))
+
+	mockCtx.EXPECT().MockWorkflowClient("Signup", "user-1", restate.RejectPromiseHandlerName).
+		Request(matchJSON(`{"name":"approved","code":500,"message":"failed"}`), mock.Anything).Return(nil).Once()
+	require.NoError(t, handle.RejectPromise("approved", fmt.Errorf("failed")))
+}
+
+func TestEnableWorkflowPromises(t *testing.T) {
+	require.Panics(t, func() {
+		restate.EnableWorkflowPromises(restate.NewService("Greeter"))
+	})
+
+	handlers := restate.EnableWorkflowPromises(restate.NewWorkflow("Signup", restate.WithValidator(restate.StructTagValidator))).Handlers()
+	require.NotNil(t, handlers[restate.ResolvePromiseHandlerName].GetOptions().Validator)
+	require.NotNil(t, handlers[restate.RejectPromiseHandlerName].GetOptions().Validator)
+	mockCtx := mocks.NewMockContext(t)
+	mockCtx.EXPECT().Request().Return(&restate.Request{}).Maybe()
+
+	// The value is resolved as encoded by the caller
+	email := base64.StdEncoding.EncodeToString([]byte(`{"email":"alice@example.com"}`))
+	verified := mocks.NewMockDurablePromise(t)
+	mockCtx.EXPECT().Promise("verified", restate.WithBinary).Return(verified).Twice()
+	verified.EXPECT().Resolve([]byte(`{"email":"alice@example.com"}`)).Return(nil).Once()
+	_, err := handlers[restate.ResolvePromiseHandlerName].Call(mockCtx, []byte(`{"name":"verified","value":"`+email+`"}`))
+	require.NoError(t, err)
+
+	verified.EXPECT().Resolve([]byte("true")).Return(restate.ToTerminalError(fmt.Errorf("promise already completed"), restate.WithErrorCode(409))).Once()
+	_, err = handlers[restate.ResolvePromiseHandlerName].Call(mockCtx, []byte(`{"name":"verified","value":"dHJ1ZQ=="}`))
+	require.Equal(t, restate.Code(409), restate.AsTerminalError(err).Code())
+
+	approved := mocks.NewMockDurablePromise(t)
+	mockCtx.EXPECT().Promise("approved").Return(approved).Twice()
+	approved.EXPECT().Reject(mock.MatchedBy(func(err error) bool {
+		terminal := restate.AsTerminalError(err)
+		return terminal.Code() == 403 && terminal.Message() == "denied"
+	})).Return(nil).Once()
+	_, err = handlers[restate.RejectPromiseHandlerName].Call(mockCtx, []byte(`{"name":"approved","code":403,"message":"denied"}`))
+	require.NoError(t, err)
+
+	// Rejections without a code fail with a 500
+	approved.EXPECT().Reject(matchCode(500)).Return(nil).Once()
+	_, err = handlers[restate.RejectPromiseHandlerName].Call(mockCtx, []byte(`{"name":"approved","message":"failed"}`))
+	require.NoError(t, err)
+}